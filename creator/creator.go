@@ -0,0 +1,345 @@
+package creator
+
+import (
+	"context"
+	"crypto/sha1"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"metainfo"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+/* Options */
+
+const (
+	MIN_PIECE_LENGTH int64 = 16 * 1024
+	MAX_PIECE_LENGTH int64 = 16 * 1024 * 1024
+	// Automatic piece length aims for about this many pieces.
+	TARGET_PIECE_COUNT = 1500
+)
+
+type Options struct {
+	// File or directory to share.
+	Path string
+	// Power of two between MIN_PIECE_LENGTH and MAX_PIECE_LENGTH, or zero to
+	// pick one based on the total size.
+	PieceLength int64
+	// Announce URLs grouped by tier.
+	Trackers [][]string
+	WebSeeds []string
+	Comment string
+	Private bool
+	CreatedBy string
+	// Number of hashing goroutines, zero means one per CPU.
+	Workers int
+}
+
+// Called from hashing goroutines with the number of bytes hashed so far.
+type ProgressFunc func(hashed int64, total int64)
+
+/* Files */
+
+type file struct {
+	path string
+	// Path components relative to Options.Path.
+	components []string
+	length int64
+	// Position of the file within the concatenated data.
+	offset int64
+}
+
+// Regular files under the path, in lexical order. Symlinks are skipped.
+func collect(root string) ([]file, int64, error) {
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if info.Mode().IsRegular() {
+		return []file{ { root, nil, info.Size(), 0 } }, info.Size(), nil
+	}
+
+	if !info.IsDir() {
+		return nil, 0, fmt.Errorf("%s is neither a file nor a directory", root)
+	}
+
+	files := []file{}
+	var total int64
+	err = filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		relative, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+
+		components := strings.Split(filepath.ToSlash(relative), "/")
+		files = append(files, file{ path, components, info.Size(), total })
+		total += info.Size()
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if len(files) == 0 {
+		return nil, 0, fmt.Errorf("%s has no files", root)
+	}
+	return files, total, nil
+}
+
+// Smallest power of two giving no more than TARGET_PIECE_COUNT pieces,
+// within the allowed range.
+func AutoPieceLength(total int64) int64 {
+	length := MIN_PIECE_LENGTH
+	for length < MAX_PIECE_LENGTH && total / length > TARGET_PIECE_COUNT {
+		length *= 2
+	}
+	return length
+}
+
+func validPieceLength(length int64) bool {
+	return length >= MIN_PIECE_LENGTH && length <= MAX_PIECE_LENGTH && length & (length - 1) == 0
+}
+
+/* Creating */
+
+// Hashes the files and returns bencoded .torrent contents.
+func Create(ctx context.Context, options Options, progress ProgressFunc) ([]byte, error) {
+	root, err := filepath.Abs(options.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	files, total, err := collect(root)
+	if err != nil {
+		return nil, err
+	}
+	if total == 0 {
+		return nil, errors.New("Can't create a torrent of empty files")
+	}
+
+	pieceLength := options.PieceLength
+	if pieceLength == 0 {
+		pieceLength = AutoPieceLength(total)
+	}
+	if !validPieceLength(pieceLength) {
+		return nil, fmt.Errorf(
+			"Piece size must be a power of two between %d and %d bytes",
+			MIN_PIECE_LENGTH, MAX_PIECE_LENGTH)
+	}
+
+	pieces, err := hashPieces(ctx, files, total, pieceLength, options.Workers, progress)
+	if err != nil {
+		return nil, err
+	}
+
+	info := map[string]interface{}{
+		"name": filepath.Base(root),
+		"piece length": pieceLength,
+		"pieces": pieces,
+	}
+	if options.Private {
+		info["private"] = 1
+	}
+
+	if len(files) == 1 && files[0].components == nil {
+		info["length"] = total
+	} else {
+		list := make([]interface{}, len(files))
+		for index, file := range files {
+			list[index] = map[string]interface{}{
+				"length": file.length,
+				"path": file.components,
+			}
+		}
+		info["files"] = list
+	}
+
+	torrent := map[string]interface{}{
+		"info": info,
+		"creation date": time.Now().Unix(),
+	}
+
+	tiers := []interface{}{}
+	for _, tier := range options.Trackers {
+		if len(tier) > 0 {
+			tiers = append(tiers, tier)
+		}
+	}
+	if len(tiers) > 0 {
+		// Old clients only know the single 'announce' URL.
+		torrent["announce"] = tiers[0].([]string)[0]
+		torrent["announce-list"] = tiers
+	}
+
+	if len(options.WebSeeds) > 0 {
+		torrent["url-list"] = options.WebSeeds
+	}
+	if options.Comment != "" {
+		torrent["comment"] = options.Comment
+	}
+	if options.CreatedBy != "" {
+		torrent["created by"] = options.CreatedBy
+	}
+
+	return metainfo.Encode(torrent)
+}
+
+// Creates the torrent and writes it to the output path. An existing file is
+// refused before hashing starts, with an error satisfying os.IsExist, unless
+// overwriting is asked for.
+func CreateFile(
+	ctx context.Context,
+	options Options,
+	output string,
+	overwrite bool,
+	progress ProgressFunc,
+) error {
+	if overwrite {
+		// Existing file stays intact if hashing fails.
+		data, err := Create(ctx, options, progress)
+		if err != nil {
+			return err
+		}
+		return ioutil.WriteFile(output, data, 0644)
+	}
+
+	handle, err := os.OpenFile(output, os.O_CREATE | os.O_EXCL | os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	data, err := Create(ctx, options, progress)
+	if err == nil {
+		_, err = handle.Write(data)
+	}
+	if closeErr := handle.Close(); err == nil {
+		err = closeErr
+	}
+
+	// File didn't exist before, don't leave a broken one behind.
+	if err != nil {
+		os.Remove(output)
+	}
+	return err
+}
+
+/* Hashing */
+
+// Concatenated SHA-1 hashes of all pieces, computed by a pool of workers.
+func hashPieces(
+	ctx context.Context,
+	files []file,
+	total int64,
+	pieceLength int64,
+	workers int,
+	progress ProgressFunc,
+) ([]byte, error) {
+	count := int((total + pieceLength - 1) / pieceLength)
+	hashes := make([]byte, count * sha1.Size)
+
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	indexes := make(chan int)
+	go func() {
+		defer close(indexes)
+		for index := 0; index < count; index++ {
+			select {
+			case indexes <- index:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	data := dataFiles(files)
+
+	var hashed int64
+	var failure error
+	var failureOnce sync.Once
+	var group sync.WaitGroup
+
+	for worker := 0; worker < workers; worker++ {
+		group.Add(1)
+		go func() {
+			defer group.Done()
+
+			reader := metainfo.NewPieceReader(data)
+			defer reader.Close()
+
+			buffer := make([]byte, pieceLength)
+			for index := range indexes {
+				start := int64(index) * pieceLength
+				length := pieceLength
+				if start + length > total {
+					length = total - start
+				}
+
+				if err := reader.ReadRange(buffer[:length], start); err != nil {
+					failureOnce.Do(func() { failure = readError(err) })
+					cancel()
+					return
+				}
+
+				sum := sha1.Sum(buffer[:length])
+				copy(hashes[index * sha1.Size:], sum[:])
+
+				done := atomic.AddInt64(&hashed, length)
+				if progress != nil {
+					progress(done, total)
+				}
+			}
+		}()
+	}
+
+	group.Wait()
+
+	if failure != nil {
+		return nil, failure
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return hashes, nil
+}
+
+// Files for reading pieces from.
+func dataFiles(files []file) []metainfo.DataFile {
+	output := make([]metainfo.DataFile, len(files))
+	for index, file := range files {
+		output[index] = metainfo.DataFile{ Path: file.path, Length: file.length, Offset: file.offset }
+	}
+	return output
+}
+
+// Files are stat'ed before hashing, so they can only end early if they
+// change in the meantime.
+func readError(err error) error {
+	var short *metainfo.ShortFileError
+	switch {
+	case errors.As(err, &short):
+		return fmt.Errorf("%s got shorter while hashing", short.Path)
+	case err == io.ErrUnexpectedEOF:
+		return errors.New("Files got shorter while hashing")
+	}
+	return err
+}
@@ -0,0 +1,253 @@
+package creator
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"io/ioutil"
+	"metainfo"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// Writes files with distinct contents under the directory, creating
+// subdirectories as needed.
+func writeFiles(t *testing.T, directory string, files map[string]int) {
+	seed := 1
+	for name, length := range files {
+		path := filepath.Join(directory, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+
+		contents := make([]byte, length)
+		for position := range contents {
+			contents[position] = byte(position * seed)
+		}
+		seed += 1
+
+		if err := ioutil.WriteFile(path, contents, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func tempDir(t *testing.T) string {
+	directory, err := ioutil.TempDir("", "creator")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return directory
+}
+
+func create(t *testing.T, options Options) *metainfo.MetaInfo {
+	data, err := Create(context.Background(), options, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := metainfo.Parse(data)
+	if err != nil {
+		t.Fatalf("Created torrent doesn't parse: %s", err)
+	}
+	return info
+}
+
+func TestSingleFile(t *testing.T) {
+	directory := tempDir(t)
+	defer os.RemoveAll(directory)
+	writeFiles(t, directory, map[string]int{ "single.bin": 40000 })
+
+	info := create(t, Options{
+		Path: filepath.Join(directory, "single.bin"),
+		PieceLength: MIN_PIECE_LENGTH,
+		Comment: "Comment",
+		Private: true,
+		CreatedBy: "test",
+	})
+
+	expected := []metainfo.File{ { Path: "single.bin", Length: 40000 } }
+	if info.Name != "single.bin" || !reflect.DeepEqual(info.Files, expected) {
+		t.Errorf("Unexpected name and files: %s, %v", info.Name, info.Files)
+	}
+	if info.PieceLength != MIN_PIECE_LENGTH || info.PieceCount != 3 {
+		t.Errorf("Expected 3 pieces of %d, got %d of %d", MIN_PIECE_LENGTH, info.PieceCount, info.PieceLength)
+	}
+	if !info.Private || info.Comment != "Comment" || info.CreatedBy != "test" {
+		t.Errorf("Options weren't written: %+v", info)
+	}
+}
+
+func TestDirectory(t *testing.T) {
+	directory := tempDir(t)
+	defer os.RemoveAll(directory)
+
+	root := filepath.Join(directory, "album")
+	writeFiles(t, root, map[string]int{
+		"cd2/02.flac": 30000,
+		"cd1/01.flac": 20000,
+		"cover.jpg": 5000,
+		"cd1/empty": 0,
+	})
+
+	info := create(t, Options{ Path: root, PieceLength: MIN_PIECE_LENGTH })
+
+	expected := []metainfo.File{
+		{ Path: "album/cd1/01.flac", Length: 20000 },
+		{ Path: "album/cd1/empty", Length: 0 },
+		{ Path: "album/cd2/02.flac", Length: 30000 },
+		{ Path: "album/cover.jpg", Length: 5000 },
+	}
+	if info.Name != "album" || !reflect.DeepEqual(info.Files, expected) {
+		t.Errorf("Expected files %v in 'album', got %v in '%s'", expected, info.Files, info.Name)
+	}
+	if info.TotalSize() != 55000 || info.PieceCount != 4 {
+		t.Errorf("Expected 55000 bytes in 4 pieces, got %d in %d", info.TotalSize(), info.PieceCount)
+	}
+}
+
+func TestTrackers(t *testing.T) {
+	directory := tempDir(t)
+	defer os.RemoveAll(directory)
+	writeFiles(t, directory, map[string]int{ "file": 100 })
+
+	trackers := [][]string{
+		{ "http://a.example.org/announce", "http://b.example.org/announce" },
+		{},
+		{ "udp://c.example.org:6969/announce" },
+	}
+	data, err := Create(
+		context.Background(),
+		Options{ Path: filepath.Join(directory, "file"), Trackers: trackers },
+		nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	value, err := metainfo.Decode(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	torrent := value.(map[string]interface{})
+
+	// First tracker of the first tier for old clients, empty tiers dropped.
+	if announce := torrent["announce"]; announce != "http://a.example.org/announce" {
+		t.Errorf("Unexpected announce: %v", announce)
+	}
+	expected := []interface{}{
+		[]interface{}{ "http://a.example.org/announce", "http://b.example.org/announce" },
+		[]interface{}{ "udp://c.example.org:6969/announce" },
+	}
+	if list := torrent["announce-list"]; !reflect.DeepEqual(list, expected) {
+		t.Errorf("Expected announce-list %v, got %v", expected, list)
+	}
+
+	// No trackers, no keys.
+	data, err = Create(context.Background(), Options{ Path: filepath.Join(directory, "file") }, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	value, err = metainfo.Decode(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	torrent = value.(map[string]interface{})
+	if _, present := torrent["announce"]; present {
+		t.Errorf("Expected no announce without trackers")
+	}
+	if _, present := torrent["announce-list"]; present {
+		t.Errorf("Expected no announce-list without trackers")
+	}
+}
+
+func TestAutoPieceLength(t *testing.T) {
+	cases := []struct {
+		total int64
+		expected int64
+	}{
+		{ 0, MIN_PIECE_LENGTH },
+		{ MIN_PIECE_LENGTH * (TARGET_PIECE_COUNT + 1) - 1, MIN_PIECE_LENGTH },
+		{ MIN_PIECE_LENGTH * (TARGET_PIECE_COUNT + 1), 2 * MIN_PIECE_LENGTH },
+		{ 2 * MIN_PIECE_LENGTH * (TARGET_PIECE_COUNT + 1) - 1, 2 * MIN_PIECE_LENGTH },
+		{ 2 * MIN_PIECE_LENGTH * (TARGET_PIECE_COUNT + 1), 4 * MIN_PIECE_LENGTH },
+		{ MAX_PIECE_LENGTH * (TARGET_PIECE_COUNT + 1) - 1, MAX_PIECE_LENGTH },
+		// Huge data still gets the largest allowed size.
+		{ MAX_PIECE_LENGTH * TARGET_PIECE_COUNT * 100, MAX_PIECE_LENGTH },
+	}
+
+	for _, test := range cases {
+		if length := AutoPieceLength(test.total); length != test.expected {
+			t.Errorf("AutoPieceLength(%d) = %d, expected %d", test.total, length, test.expected)
+		}
+	}
+}
+
+func TestParallelHashing(t *testing.T) {
+	directory := tempDir(t)
+	defer os.RemoveAll(directory)
+
+	// Files crossing piece boundaries in different places, with an empty one
+	// in between.
+	sizes := map[string]int{ "a": 10000, "b": 0, "c": 50000, "d": 1, "e": 70000 }
+	writeFiles(t, directory, sizes)
+
+	files, total, err := collect(directory)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Reference: pieces of the concatenated data, hashed one by one.
+	var data []byte
+	for _, file := range files {
+		contents, err := ioutil.ReadFile(file.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data = append(data, contents...)
+	}
+
+	var expected []byte
+	for start := 0; start < len(data); start += int(MIN_PIECE_LENGTH) {
+		end := start + int(MIN_PIECE_LENGTH)
+		if end > len(data) {
+			end = len(data)
+		}
+		sum := sha1.Sum(data[start:end])
+		expected = append(expected, sum[:]...)
+	}
+
+	for _, workers := range []int{ 1, 3, 8 } {
+		hashes, err := hashPieces(context.Background(), files, total, MIN_PIECE_LENGTH, workers, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(hashes, expected) {
+			t.Errorf("Hashes with %d workers differ from sequential ones", workers)
+		}
+	}
+}
+
+func TestCreateFileExisting(t *testing.T) {
+	directory := tempDir(t)
+	defer os.RemoveAll(directory)
+	writeFiles(t, directory, map[string]int{ "file": 100, "file.torrent": 10 })
+
+	options := Options{ Path: filepath.Join(directory, "file") }
+	output := filepath.Join(directory, "file.torrent")
+
+	if err := CreateFile(context.Background(), options, output, false, nil); !os.IsExist(err) {
+		t.Fatalf("Expected an 'exists' error, got %v", err)
+	}
+	if data, _ := ioutil.ReadFile(output); len(data) != 10 {
+		t.Fatalf("Existing file was changed")
+	}
+
+	if err := CreateFile(context.Background(), options, output, true, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := metainfo.Load(output); err != nil {
+		t.Fatalf("Overwritten file doesn't parse: %s", err)
+	}
+}
@@ -3,20 +3,28 @@ module github.com/cog1to/transmission-go
 go 1.13
 
 require (
-    transmission v0.0.0
-    tui v0.0.0
-    windows v0.0.0
-    logger v0.0.0
+	logger v0.0.0
+	metainfo v0.0.0
+	transmission v0.0.0
+	transmissiontest v0.0.0
+	tui v0.0.0
+	verify v0.0.0
+	windows v0.0.0
 )
 
 replace (
-    transmission => ./transmission
-    tui => ./tui
-    windows => ./windows
-    worker => ./worker
-    utils => ./utils
-    transform => ./transform
-    list => ./list
-    suggestions => ./suggestions
-    logger => ./logger
+	creator => ./creator
+	list => ./list
+	logger => ./logger
+	magnet => ./magnet
+	metainfo => ./metainfo
+	suggestions => ./suggestions
+	transform => ./transform
+	transmission => ./transmission
+	transmissiontest => ./transmissiontest
+	tui => ./tui
+	utils => ./utils
+	verify => ./verify
+	windows => ./windows
+	worker => ./worker
 )
@@ -32,6 +32,7 @@ type List struct {
 	Window tui.Drawable
 	Formatter Formatter
 	MarginTop, MarginBottom, MarginLeft, MarginRight int
+	Dimmed bool
 
 	// Private
 	Cursor int
@@ -52,6 +53,10 @@ func (drawer *List) Draw() {
 	x, y := drawer.MarginLeft, drawer.MarginTop
 	for index, item := range drawer.Items[drawer.Offset:] {
 		attribute := make([]tui.Attribute, 0)
+		if drawer.Dimmed {
+			attribute = append(attribute, tui.ATTR_DIM)
+		}
+
 		if index + drawer.Offset == drawer.Cursor {
 			attribute = append(attribute, tui.ATTR_REVERSED)
 		}
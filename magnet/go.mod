@@ -0,0 +1,7 @@
+module magnet
+
+go 1.13
+
+require (
+    metainfo v0.0.0
+)
@@ -0,0 +1,220 @@
+package magnet
+
+import (
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"metainfo"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+/* Data */
+
+const (
+	BTIH_PREFIX = "urn:btih:"
+	BTMH_PREFIX = "urn:btmh:"
+	// Multihash header of a SHA2-256 digest, the only kind BitTorrent v2 uses.
+	SHA256_MULTIHASH = "1220"
+)
+
+// Parsed magnet link. At least one of the hashes is set.
+type Magnet struct {
+	// v1 info-hash, lowercase hex.
+	InfoHash string
+	// v2 info-hash as a multihash, lowercase hex.
+	InfoHashV2 string
+	DisplayName string
+	Trackers []string
+	WebSeeds []string
+	// Total size in bytes, zero if unknown.
+	Length int64
+	// Parameters not covered above, like 'x.pe' peer addresses, kept as is.
+	Extra url.Values
+}
+
+// Magnet link of a parsed torrent file.
+func FromMetaInfo(info *metainfo.MetaInfo) *Magnet {
+	trackers := []string{}
+	for _, tier := range info.Trackers {
+		trackers = append(trackers, tier...)
+	}
+
+	return &Magnet{
+		InfoHash: info.InfoHashString(),
+		DisplayName: info.Name,
+		Trackers: trackers,
+		WebSeeds: append([]string{}, info.WebSeeds...),
+		Length: info.TotalSize(),
+	}
+}
+
+/* Parsing */
+
+func Parse(uri string) (*Magnet, error) {
+	uri = strings.TrimSpace(uri)
+	if !strings.HasPrefix(strings.ToLower(uri), "magnet:?") {
+		return nil, errors.New("Not a magnet link")
+	}
+
+	query, err := url.ParseQuery(uri[len("magnet:?"):])
+	if err != nil {
+		return nil, fmt.Errorf("Malformed magnet link: %s", err)
+	}
+
+	// Numbered variants, like 'tr.1', 'tr.2', mean the same as the plain key.
+	// Sorted, so they keep their order.
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(l, r int) bool {
+		leftName, leftNumber := splitKey(keys[l])
+		rightName, rightNumber := splitKey(keys[r])
+		if leftName != rightName {
+			return leftName < rightName
+		}
+		return leftNumber < rightNumber
+	})
+
+	output := &Magnet{ Trackers: []string{}, WebSeeds: []string{}, Extra: url.Values{} }
+	for _, key := range keys {
+		name, _ := splitKey(key)
+		for _, value := range query[key] {
+			if err := output.set(key, name, value); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if output.InfoHash == "" && output.InfoHashV2 == "" {
+		return nil, errors.New("Magnet link has no info hash")
+	}
+	return output, nil
+}
+
+// Parameter name and its number, -1 if there's none.
+func splitKey(key string) (string, int) {
+	dot := strings.Index(key, ".")
+	if dot < 0 {
+		return key, -1
+	}
+
+	number, err := strconv.Atoi(key[dot + 1:])
+	if err != nil {
+		return key[:dot], -1
+	}
+	return key[:dot], number
+}
+
+func (magnet *Magnet) set(key string, name string, value string) error {
+	switch name {
+	case "xt":
+		return magnet.setTopic(value)
+	case "dn":
+		magnet.DisplayName = value
+	case "tr":
+		magnet.Trackers = append(magnet.Trackers, value)
+	case "ws":
+		magnet.WebSeeds = append(magnet.WebSeeds, value)
+	case "xl":
+		length, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || length < 0 {
+			return fmt.Errorf("Invalid length '%s'", value)
+		}
+		magnet.Length = length
+	default:
+		magnet.Extra.Add(key, value)
+	}
+	return nil
+}
+
+func (magnet *Magnet) setTopic(topic string) error {
+	lowercased := strings.ToLower(topic)
+	switch {
+	case strings.HasPrefix(lowercased, BTIH_PREFIX):
+		hash, err := parseBTIH(topic[len(BTIH_PREFIX):])
+		if err != nil {
+			return err
+		}
+		magnet.InfoHash = hash
+	case strings.HasPrefix(lowercased, BTMH_PREFIX):
+		hash, err := parseBTMH(topic[len(BTMH_PREFIX):])
+		if err != nil {
+			return err
+		}
+		magnet.InfoHashV2 = hash
+	}
+
+	// Other topics, like ed2k, are ignored.
+	return nil
+}
+
+// SHA-1 as 40 hex digits, or 32 base32 characters.
+func parseBTIH(hash string) (string, error) {
+	switch len(hash) {
+	case 40:
+		if _, err := hex.DecodeString(hash); err != nil {
+			return "", fmt.Errorf("Info hash '%s' is not valid hex", hash)
+		}
+		return strings.ToLower(hash), nil
+	case 32:
+		data, err := base32.StdEncoding.DecodeString(strings.ToUpper(hash))
+		if err != nil {
+			return "", fmt.Errorf("Info hash '%s' is not valid base32", hash)
+		}
+		return hex.EncodeToString(data), nil
+	}
+	return "", fmt.Errorf("Info hash has %d characters, expected 40 hex or 32 base32", len(hash))
+}
+
+// SHA2-256 multihash: '1220' followed by 64 hex digits.
+func parseBTMH(hash string) (string, error) {
+	hash = strings.ToLower(hash)
+	if len(hash) != len(SHA256_MULTIHASH) + 64 || !strings.HasPrefix(hash, SHA256_MULTIHASH) {
+		return "", fmt.Errorf("v2 info hash '%s' is not a SHA2-256 multihash", hash)
+	}
+	if _, err := hex.DecodeString(hash); err != nil {
+		return "", fmt.Errorf("v2 info hash '%s' is not valid hex", hash)
+	}
+	return hash, nil
+}
+
+/* Building */
+
+// Canonical form: hex hashes first, then name, size, trackers, web seeds and
+// the rest of parameters.
+func (magnet *Magnet) String() string {
+	parameters := []string{}
+	add := func(key string, value string) {
+		parameters = append(parameters, key + "=" + url.QueryEscape(value))
+	}
+
+	if magnet.InfoHash != "" {
+		// Colons are kept readable, clients expect them as is.
+		parameters = append(parameters, "xt=" + BTIH_PREFIX + magnet.InfoHash)
+	}
+	if magnet.InfoHashV2 != "" {
+		parameters = append(parameters, "xt=" + BTMH_PREFIX + magnet.InfoHashV2)
+	}
+	if magnet.DisplayName != "" {
+		add("dn", magnet.DisplayName)
+	}
+	if magnet.Length > 0 {
+		add("xl", strconv.FormatInt(magnet.Length, 10))
+	}
+	for _, tracker := range magnet.Trackers {
+		add("tr", tracker)
+	}
+	for _, seed := range magnet.WebSeeds {
+		add("ws", seed)
+	}
+	if len(magnet.Extra) > 0 {
+		parameters = append(parameters, magnet.Extra.Encode())
+	}
+
+	return "magnet:?" + strings.Join(parameters, "&")
+}
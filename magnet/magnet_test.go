@@ -0,0 +1,196 @@
+package magnet
+
+import (
+	"encoding/base32"
+	"encoding/hex"
+	"metainfo"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+const (
+	TEST_HASH = "0123456789abcdef0123456789abcdef01234567"
+	TEST_HASH_V2 = SHA256_MULTIHASH + "00112233445566778899aabbccddeeff00112233445566778899aabbccddeeff"
+)
+
+func mustParse(t *testing.T, uri string) *Magnet {
+	link, err := Parse(uri)
+	if err != nil {
+		t.Fatalf("%s: %s", uri, err)
+	}
+	return link
+}
+
+func TestInfoHash(t *testing.T) {
+	data, _ := hex.DecodeString(TEST_HASH)
+	encoded := base32.StdEncoding.EncodeToString(data)
+
+	links := []string{
+		"magnet:?xt=urn:btih:" + TEST_HASH,
+		// Uppercase hex and prefix are fine too.
+		"MAGNET:?xt=URN:BTIH:0123456789ABCDEF0123456789ABCDEF01234567",
+		"magnet:?xt=urn:btih:" + encoded,
+	}
+
+	for _, uri := range links {
+		if link := mustParse(t, uri); link.InfoHash != TEST_HASH || link.InfoHashV2 != "" {
+			t.Errorf("%s: unexpected hashes %q, %q", uri, link.InfoHash, link.InfoHashV2)
+		}
+	}
+}
+
+func TestInfoHashV2(t *testing.T) {
+	link := mustParse(t, "magnet:?xt=urn:btmh:" + TEST_HASH_V2)
+	if link.InfoHashV2 != TEST_HASH_V2 || link.InfoHash != "" {
+		t.Errorf("Unexpected hashes %q, %q", link.InfoHash, link.InfoHashV2)
+	}
+
+	// Hybrid torrents have both.
+	link = mustParse(t, "magnet:?xt=urn:btih:" + TEST_HASH + "&xt=urn:btmh:" + TEST_HASH_V2)
+	if link.InfoHash != TEST_HASH || link.InfoHashV2 != TEST_HASH_V2 {
+		t.Errorf("Unexpected hybrid hashes %q, %q", link.InfoHash, link.InfoHashV2)
+	}
+}
+
+func TestNumberedKeys(t *testing.T) {
+	link := mustParse(
+		t,
+		"magnet:?xt.1=urn:btih:" + TEST_HASH +
+			"&xt.2=urn:btmh:" + TEST_HASH_V2 +
+			"&tr.2=http://b.example.org/announce" +
+			"&tr.10=http://c.example.org/announce" +
+			"&tr.1=http://a.example.org/announce")
+
+	if link.InfoHash != TEST_HASH || link.InfoHashV2 != TEST_HASH_V2 {
+		t.Errorf("Unexpected hashes %q, %q", link.InfoHash, link.InfoHashV2)
+	}
+
+	// Ordered by number, not by text.
+	expected := []string{
+		"http://a.example.org/announce",
+		"http://b.example.org/announce",
+		"http://c.example.org/announce",
+	}
+	if !reflect.DeepEqual(link.Trackers, expected) {
+		t.Errorf("Expected trackers %v, got %v", expected, link.Trackers)
+	}
+}
+
+func TestRepeatedKeys(t *testing.T) {
+	link := mustParse(
+		t,
+		"magnet:?xt=urn:btih:" + TEST_HASH +
+			"&tr=udp%3A%2F%2Fa.example.org%3A6969" +
+			"&tr=http://b.example.org/announce" +
+			"&ws=http://seed.example.org/a" +
+			"&ws=http://seed.example.org/b" +
+			"&dn=Some+name&xl=1024")
+
+	trackers := []string{ "udp://a.example.org:6969", "http://b.example.org/announce" }
+	if !reflect.DeepEqual(link.Trackers, trackers) {
+		t.Errorf("Expected trackers %v, got %v", trackers, link.Trackers)
+	}
+
+	seeds := []string{ "http://seed.example.org/a", "http://seed.example.org/b" }
+	if !reflect.DeepEqual(link.WebSeeds, seeds) {
+		t.Errorf("Expected web seeds %v, got %v", seeds, link.WebSeeds)
+	}
+
+	if link.DisplayName != "Some name" || link.Length != 1024 {
+		t.Errorf("Unexpected name and length: %q, %d", link.DisplayName, link.Length)
+	}
+}
+
+func TestExtra(t *testing.T) {
+	link := mustParse(
+		t,
+		"magnet:?xt=urn:btih:" + TEST_HASH + "&x.pe=10.0.0.1:6881&x.pe=10.0.0.2:6881&so=0,2&kt=word")
+
+	expected := url.Values{
+		"x.pe": []string{ "10.0.0.1:6881", "10.0.0.2:6881" },
+		"so": []string{ "0,2" },
+		"kt": []string{ "word" },
+	}
+	if !reflect.DeepEqual(link.Extra, expected) {
+		t.Errorf("Expected extra parameters %v, got %v", expected, link.Extra)
+	}
+}
+
+func TestInvalid(t *testing.T) {
+	links := map[string]string{
+		"wrong scheme": "http://example.org/?xt=urn:btih:" + TEST_HASH,
+		"short hash": "magnet:?xt=urn:btih:0123456789abcdef",
+		"long hash": "magnet:?xt=urn:btih:" + TEST_HASH + "00",
+		"not hex": "magnet:?xt=urn:btih:0123456789abcdef0123456789abcdef0123456z",
+		"not base32": "magnet:?xt=urn:btih:01234567890123456789012345678901",
+		"bad multihash": "magnet:?xt=urn:btmh:1114" + TEST_HASH_V2[4:],
+		"no xt": "magnet:?dn=name&tr=http://a.example.org/announce",
+		"other topic only": "magnet:?xt=urn:ed2k:31d6cfe0d16ae931b73c59d7e0c089c0",
+		"bad length": "magnet:?xt=urn:btih:" + TEST_HASH + "&xl=-1",
+	}
+
+	for description, uri := range links {
+		if link, err := Parse(uri); err == nil {
+			t.Errorf("%s: expected an error, got %+v", description, link)
+		}
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	links := []string{
+		"magnet:?xt=urn:btih:" + TEST_HASH,
+		"magnet:?xt=urn:btmh:" + TEST_HASH_V2 + "&dn=v2+only",
+		"magnet:?xt=urn:btih:" + TEST_HASH +
+			"&xt=urn:btmh:" + TEST_HASH_V2 +
+			"&dn=Name+with+%26+and+%3D&xl=123456" +
+			"&tr.1=http://a.example.org/announce?key=1%262" +
+			"&tr.2=udp://b.example.org:6969" +
+			"&ws=http://seed.example.org/path%20with%20spaces" +
+			"&x.pe=10.0.0.1:6881&so=0-3",
+	}
+
+	for _, uri := range links {
+		link := mustParse(t, uri)
+		again := mustParse(t, link.String())
+		if !reflect.DeepEqual(link, again) {
+			t.Errorf("%s didn't survive a round trip:\n%+v\n%+v", uri, link, again)
+		}
+	}
+}
+
+func TestFromMetaInfo(t *testing.T) {
+	info := &metainfo.MetaInfo{
+		Name: "example",
+		Files: []metainfo.File{ { Path: "example/a", Length: 100 }, { Path: "example/b", Length: 50 } },
+		Trackers: [][]string{
+			{ "http://a.example.org/announce", "http://b.example.org/announce" },
+			{ "udp://c.example.org:6969" },
+		},
+		WebSeeds: []string{ "http://seed.example.org/" },
+	}
+	copy(info.InfoHash[:], []byte("0123456789abcdefghij"))
+
+	link := FromMetaInfo(info)
+	expected := &Magnet{
+		InfoHash: info.InfoHashString(),
+		DisplayName: "example",
+		Trackers: []string{
+			"http://a.example.org/announce",
+			"http://b.example.org/announce",
+			"udp://c.example.org:6969",
+		},
+		WebSeeds: []string{ "http://seed.example.org/" },
+		Length: 150,
+	}
+	if !reflect.DeepEqual(link, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, link)
+	}
+
+	// Link of the torrent parses back to the same thing.
+	again := mustParse(t, link.String())
+	again.Extra = nil
+	if !reflect.DeepEqual(again, expected) {
+		t.Errorf("Link %s parses to %+v", link.String(), again)
+	}
+}
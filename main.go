@@ -4,9 +4,18 @@ package main
 import "C"
 
 import (
+	"context"
+	"encoding/json"
+	"os"
 	"os/exec"
 	"flag"
+	"fmt"
+	"metainfo"
+	"path/filepath"
+	"strings"
 	"transmission"
+	"transmissiontest"
+	"verify"
 	"windows"
 	"tui"
 )
@@ -17,16 +26,71 @@ func main() {
 	// Command line arguments.
 	var host = flag.String("h", "localhost", "Hostname")
 	var port = flag.Int("p", 9091, "Port")
+	var rpcUrl = flag.String("url", "", "Full RPC URL, like `https://host:443/transmission/rpc/`. Overrides -h and -p")
+	var caFile = flag.String("cacert", "", "PEM `file` with CA certificates to trust")
+	var certFile = flag.String("cert", "", "Client certificate PEM `file`")
+	var keyFile = flag.String("key", "", "Client certificate key PEM `file`, if not bundled with the certificate")
+	var insecure = flag.Bool("insecure", false, "Skip TLS certificate verification")
 	var obfuscate = flag.Bool("o", false, "Obfuscate torrent and file names")
 	var launch = flag.Bool("s", false, "Launch `transmission-daemon` before starting the client")
+	var demo = flag.Bool("demo", false, "Run against a built-in simulated daemon")
+	var verifyTorrent = flag.String("verify", "", "Check local data against a .torrent `file`, print a JSON report and exit")
+	var verifyData = flag.String("data", "", "`Directory` with the data to check, defaults to the .torrent's directory")
+	var auth string
+	flag.StringVar(&auth, "u", "", "Credentials for RPC authentication, `username:password`")
+	flag.StringVar(&auth, "auth", "", "Same as -u")
 	flag.Parse()
 
+	// Local verification doesn't need the daemon.
+	if *verifyTorrent != "" {
+		os.Exit(runVerify(*verifyTorrent, *verifyData))
+	}
+
+	// Connection spec.
+	var connection transmission.Connection
+	if *demo {
+		// Fixed seed, so every demo run starts with the same torrents.
+		server := transmissiontest.NewServer(transmissiontest.NewDemoDaemon(1))
+		defer server.Close()
+		connection = server.Connection()
+	} else if *rpcUrl != "" {
+		var err error
+		connection, err = transmission.ParseConnection(*rpcUrl)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+	} else {
+		connection = transmission.NewConnection(*host, int32(*port))
+	}
+
+	connection.TLS = transmission.TLSOptions{
+		CAFile: *caFile,
+		CertFile: *certFile,
+		KeyFile: *keyFile,
+		InsecureSkipVerify: *insecure,
+	}
+
+	// Split credentials. Password may contain colons, username may not.
+	if auth != "" {
+		parts := strings.SplitN(auth, ":", 2)
+		connection.Username = parts[0]
+		connection.Password = ""
+		if len(parts) > 1 {
+			connection.Password = parts[1]
+		}
+	}
+
 	// Initialize daemon connection
-	client := transmission.NewClient(*host, int32(*port))
+	client, err := transmission.NewClient(connection)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
 
 	// If launch was requested in the arguments,
 	// check for existing daemon first, and launch a new instance if needed.
-	if *launch == true {
+	if *launch == true && !*demo {
 		_, err := client.GetSessionSettings()
 		if err != nil {
 			cmd := exec.Command("transmission-daemon")
@@ -59,3 +123,35 @@ func main() {
 	manager.Start()
 }
 
+
+// Prints the verification report. Exit code is 0 if all data is good, 1 if
+// not, 2 if verification failed.
+func runVerify(torrent string, directory string) int {
+	info, err := metainfo.Load(torrent)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	if directory == "" {
+		directory = filepath.Dir(torrent)
+	}
+
+	report, err := verify.Verify(context.Background(), info, directory, 0, nil)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	if !report.Complete {
+		return 1
+	}
+	return 0
+}
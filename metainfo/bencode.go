@@ -0,0 +1,279 @@
+package metainfo
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+/* Bencode */
+
+// Nesting limit, so malformed input can't exhaust the stack.
+const MAX_DEPTH = 256
+
+// Malformed bencode.
+type SyntaxError struct {
+	Offset int
+	Message string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("Invalid bencode at offset %d: %s", e.Offset, e.Message)
+}
+
+// Decodes a single bencoded value. Integers become int64, byte strings become
+// string, lists become []interface{} and dictionaries map[string]interface{}.
+// Trailing data is an error.
+func Decode(data []byte) (interface{}, error) {
+	decoder := &decoder{ data, 0, 0 }
+
+	value, err := decoder.value()
+	if err != nil {
+		return nil, err
+	}
+
+	if decoder.pos != len(data) {
+		return nil, decoder.error("trailing data")
+	}
+	return value, nil
+}
+
+type decoder struct {
+	data []byte
+	pos int
+	depth int
+}
+
+func (decoder *decoder) error(format string, args ...interface{}) error {
+	return &SyntaxError{ decoder.pos, fmt.Sprintf(format, args...) }
+}
+
+func (decoder *decoder) peek() (byte, error) {
+	if decoder.pos >= len(decoder.data) {
+		return 0, decoder.error("unexpected end of data")
+	}
+	return decoder.data[decoder.pos], nil
+}
+
+func (decoder *decoder) value() (interface{}, error) {
+	next, err := decoder.peek()
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case next == 'i':
+		return decoder.integer()
+	case next >= '0' && next <= '9':
+		return decoder.string()
+	case next == 'l':
+		return decoder.list()
+	case next == 'd':
+		return decoder.dictionary(nil)
+	}
+	return nil, decoder.error("unexpected '%c'", next)
+}
+
+func (decoder *decoder) integer() (int64, error) {
+	// Skip 'i'.
+	decoder.pos += 1
+
+	end := decoder.find('e')
+	if end < 0 {
+		return 0, decoder.error("unterminated integer")
+	}
+
+	text := string(decoder.data[decoder.pos:end])
+	digits := text
+	if len(digits) > 0 && digits[0] == '-' {
+		digits = digits[1:]
+	}
+
+	// No empty, '-0' or zero-padded numbers.
+	if len(digits) == 0 || (digits[0] == '0' && (len(digits) > 1 || len(text) > 1)) {
+		return 0, decoder.error("invalid integer '%s'", text)
+	}
+
+	number, err := strconv.ParseInt(text, 10, 64)
+	if err != nil {
+		return 0, decoder.error("invalid integer '%s'", text)
+	}
+
+	decoder.pos = end + 1
+	return number, nil
+}
+
+func (decoder *decoder) string() (string, error) {
+	end := decoder.find(':')
+	if end < 0 {
+		return "", decoder.error("unterminated string length")
+	}
+
+	text := string(decoder.data[decoder.pos:end])
+	if len(text) > 1 && text[0] == '0' {
+		return "", decoder.error("invalid string length '%s'", text)
+	}
+
+	length, err := strconv.Atoi(text)
+	if err != nil || length < 0 {
+		return "", decoder.error("invalid string length '%s'", text)
+	}
+
+	start := end + 1
+	if length > len(decoder.data) - start {
+		return "", decoder.error("string is longer than the data")
+	}
+
+	decoder.pos = start + length
+	return string(decoder.data[start:decoder.pos]), nil
+}
+
+func (decoder *decoder) list() ([]interface{}, error) {
+	if err := decoder.enter(); err != nil {
+		return nil, err
+	}
+	defer decoder.leave()
+
+	list := []interface{}{}
+	for {
+		next, err := decoder.peek()
+		if err != nil {
+			return nil, err
+		}
+
+		if next == 'e' {
+			decoder.pos += 1
+			return list, nil
+		}
+
+		item, err := decoder.value()
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, item)
+	}
+}
+
+// Calls visit, if provided, with raw bytes of every value, so callers can
+// hash parts of the input exactly as they were encoded.
+func (decoder *decoder) dictionary(visit func(key string, raw []byte)) (map[string]interface{}, error) {
+	if err := decoder.enter(); err != nil {
+		return nil, err
+	}
+	defer decoder.leave()
+
+	dictionary := map[string]interface{}{}
+	for {
+		next, err := decoder.peek()
+		if err != nil {
+			return nil, err
+		}
+
+		if next == 'e' {
+			decoder.pos += 1
+			return dictionary, nil
+		}
+
+		if next < '0' || next > '9' {
+			return nil, decoder.error("dictionary key is not a string")
+		}
+
+		key, err := decoder.string()
+		if err != nil {
+			return nil, err
+		}
+
+		start := decoder.pos
+		item, err := decoder.value()
+		if err != nil {
+			return nil, err
+		}
+
+		if visit != nil {
+			visit(key, decoder.data[start:decoder.pos])
+		}
+		dictionary[key] = item
+	}
+}
+
+func (decoder *decoder) enter() error {
+	if decoder.depth >= MAX_DEPTH {
+		return decoder.error("nesting is too deep")
+	}
+
+	// Skip 'l' or 'd'.
+	decoder.pos += 1
+	decoder.depth += 1
+	return nil
+}
+
+func (decoder *decoder) leave() {
+	decoder.depth -= 1
+}
+
+// Index of the next occurrence of the symbol, or -1.
+func (decoder *decoder) find(symbol byte) int {
+	for index := decoder.pos; index < len(decoder.data); index++ {
+		if decoder.data[index] == symbol {
+			return index
+		}
+	}
+	return -1
+}
+
+// Encodes integers, strings, byte slices, lists and dictionaries with string
+// keys. Dictionary keys are sorted, as the spec requires.
+func Encode(value interface{}) ([]byte, error) {
+	var buffer bytes.Buffer
+	if err := encode(&buffer, value); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+func encode(buffer *bytes.Buffer, value interface{}) error {
+	switch typed := value.(type) {
+	case int:
+		fmt.Fprintf(buffer, "i%de", typed)
+	case int64:
+		fmt.Fprintf(buffer, "i%de", typed)
+	case string:
+		fmt.Fprintf(buffer, "%d:%s", len(typed), typed)
+	case []byte:
+		fmt.Fprintf(buffer, "%d:", len(typed))
+		buffer.Write(typed)
+	case []string:
+		buffer.WriteByte('l')
+		for _, item := range typed {
+			fmt.Fprintf(buffer, "%d:%s", len(item), item)
+		}
+		buffer.WriteByte('e')
+	case []interface{}:
+		buffer.WriteByte('l')
+		for _, item := range typed {
+			if err := encode(buffer, item); err != nil {
+				return err
+			}
+		}
+		buffer.WriteByte('e')
+	case map[string]interface{}:
+		keys := make([]string, 0, len(typed))
+		for key := range typed {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		buffer.WriteByte('d')
+		for _, key := range keys {
+			fmt.Fprintf(buffer, "%d:%s", len(key), key)
+			if err := encode(buffer, typed[key]); err != nil {
+				return err
+			}
+		}
+		buffer.WriteByte('e')
+	default:
+		return fmt.Errorf("Can't bencode %T", value)
+	}
+	return nil
+}
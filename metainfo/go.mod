@@ -0,0 +1,3 @@
+module metainfo
+
+go 1.13
@@ -0,0 +1,268 @@
+package metainfo
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"strings"
+	"time"
+)
+
+/* Data */
+
+type File struct {
+	// Components joined with '/', starting with the torrent's name for
+	// multi-file torrents.
+	Path string
+	Length int64
+}
+
+// Contents of a .torrent file.
+type MetaInfo struct {
+	Name string
+	Files []File
+	PieceLength int64
+	PieceCount int
+	// Concatenated SHA-1 hashes of all pieces.
+	Pieces []byte
+	// Announce URLs grouped by tier.
+	Trackers [][]string
+	WebSeeds []string
+	Private bool
+	Comment string
+	CreatedBy string
+	// Zero if not specified.
+	CreationDate time.Time
+	// SHA-1 of the bencoded 'info' dictionary.
+	InfoHash [20]byte
+}
+
+func (info *MetaInfo) TotalSize() int64 {
+	var total int64
+	for _, file := range info.Files {
+		total += file.Length
+	}
+	return total
+}
+
+// Expected SHA-1 of the piece.
+func (info *MetaInfo) PieceHash(index int) []byte {
+	return info.Pieces[index * sha1.Size:(index + 1) * sha1.Size]
+}
+
+// Length of the piece, the last one is usually shorter.
+func (info *MetaInfo) PieceSize(index int) int64 {
+	if index == info.PieceCount - 1 {
+		return info.TotalSize() - int64(index) * info.PieceLength
+	}
+	return info.PieceLength
+}
+
+// Lowercase hex, the way the daemon reports 'hashString'.
+func (info *MetaInfo) InfoHashString() string {
+	return hex.EncodeToString(info.InfoHash[:])
+}
+
+// Number of announce URLs across all tiers.
+func (info *MetaInfo) TrackerCount() int {
+	count := 0
+	for _, tier := range info.Trackers {
+		count += len(tier)
+	}
+	return count
+}
+
+/* Parsing */
+
+// Reads and parses a .torrent file.
+func Load(path string) (*MetaInfo, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+func Parse(data []byte) (*MetaInfo, error) {
+	decoder := &decoder{ data, 0, 0 }
+
+	next, err := decoder.peek()
+	if err != nil {
+		return nil, err
+	}
+	if next != 'd' {
+		return nil, errors.New("Torrent file is not a dictionary")
+	}
+
+	// Info-hash must be computed over the original bytes, re-encoding could
+	// change them.
+	var rawInfo []byte
+	root, err := decoder.dictionary(func(key string, raw []byte) {
+		if key == "info" {
+			rawInfo = raw
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	if decoder.pos != len(data) {
+		return nil, decoder.error("trailing data")
+	}
+
+	info, ok := root["info"].(map[string]interface{})
+	if !ok {
+		return nil, errors.New("Torrent file has no info dictionary")
+	}
+
+	output := &MetaInfo{
+		InfoHash: sha1.Sum(rawInfo),
+		Comment: utf8String(root, "comment"),
+		CreatedBy: utf8String(root, "created by"),
+		Trackers: trackers(root),
+		WebSeeds: stringList(root["url-list"]),
+	}
+
+	if date, ok := root["creation date"].(int64); ok && date > 0 {
+		output.CreationDate = time.Unix(date, 0)
+	}
+
+	if err := output.parseInfo(info); err != nil {
+		return nil, err
+	}
+	return output, nil
+}
+
+func (info *MetaInfo) parseInfo(dictionary map[string]interface{}) error {
+	info.Name = utf8String(dictionary, "name")
+	if info.Name == "" {
+		return errors.New("Torrent has no name")
+	}
+
+	private, _ := dictionary["private"].(int64)
+	info.Private = private == 1
+
+	pieceLength, _ := dictionary["piece length"].(int64)
+	if pieceLength <= 0 {
+		return errors.New("Torrent has no piece length")
+	}
+	info.PieceLength = pieceLength
+
+	// v2-only torrents have no piece hashes and no v1 info-hash.
+	pieces, ok := dictionary["pieces"].(string)
+	if !ok {
+		return errors.New("Torrent has no v1 piece hashes")
+	}
+	if len(pieces) % sha1.Size != 0 {
+		return fmt.Errorf("Piece hashes have invalid length %d", len(pieces))
+	}
+	info.PieceCount = len(pieces) / sha1.Size
+	info.Pieces = []byte(pieces)
+
+	// Single-file torrent.
+	if length, ok := dictionary["length"].(int64); ok {
+		if length < 0 {
+			return errors.New("Torrent has negative length")
+		}
+		info.Files = []File{ { info.Name, length } }
+		return info.checkPieceCount()
+	}
+
+	files, ok := dictionary["files"].([]interface{})
+	if !ok || len(files) == 0 {
+		return errors.New("Torrent has no files")
+	}
+
+	var total int64
+	for index, item := range files {
+		file, ok := item.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("File %d is not a dictionary", index)
+		}
+
+		length, ok := file["length"].(int64)
+		if !ok || length < 0 {
+			return fmt.Errorf("File %d has invalid length", index)
+		}
+		if length > math.MaxInt64 - total {
+			return errors.New("Torrent is too large")
+		}
+		total += length
+
+		components := stringList(file["path.utf-8"])
+		if len(components) == 0 {
+			components = stringList(file["path"])
+		}
+		if len(components) == 0 {
+			return fmt.Errorf("File %d has no path", index)
+		}
+
+		path := info.Name + "/" + strings.Join(components, "/")
+		info.Files = append(info.Files, File{ path, length })
+	}
+
+	return info.checkPieceCount()
+}
+
+// Piece hashes have to cover the data exactly, otherwise piece sizes and
+// hashes can't be looked up.
+func (info *MetaInfo) checkPieceCount() error {
+	total := info.TotalSize()
+	expected := total / info.PieceLength
+	if total % info.PieceLength != 0 {
+		expected += 1
+	}
+
+	if int64(info.PieceCount) != expected {
+		return fmt.Errorf("Torrent has %d piece hashes for %d pieces", info.PieceCount, expected)
+	}
+	return nil
+}
+
+/* Helpers */
+
+// String value, preferring the '.utf-8' variant some clients add.
+func utf8String(dictionary map[string]interface{}, key string) string {
+	if value, ok := dictionary[key + ".utf-8"].(string); ok && value != "" {
+		return value
+	}
+	value, _ := dictionary[key].(string)
+	return value
+}
+
+// String items of a list, or a single string. Other values are skipped.
+func stringList(value interface{}) []string {
+	output := []string{}
+	switch typed := value.(type) {
+	case string:
+		output = append(output, typed)
+	case []interface{}:
+		for _, item := range typed {
+			if text, ok := item.(string); ok {
+				output = append(output, text)
+			}
+		}
+	}
+	return output
+}
+
+// 'announce-list' tiers, or the single 'announce' URL if there's no list.
+func trackers(root map[string]interface{}) [][]string {
+	tiers := [][]string{}
+	if list, ok := root["announce-list"].([]interface{}); ok {
+		for _, item := range list {
+			if tier := stringList(item); len(tier) > 0 {
+				tiers = append(tiers, tier)
+			}
+		}
+	}
+
+	if len(tiers) == 0 {
+		if announce, ok := root["announce"].(string); ok && announce != "" {
+			tiers = append(tiers, []string{ announce })
+		}
+	}
+	return tiers
+}
@@ -0,0 +1,138 @@
+package metainfo
+
+import (
+	"crypto/sha1"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// Info-hashes were computed by an independent bencoder over the same files,
+// not by this package.
+var goldenTorrents = []struct {
+	file string
+	infoHash string
+	name string
+	files []File
+	pieceCount int
+	trackers [][]string
+	private bool
+}{
+	{
+		"single.torrent",
+		"036b287166f987d8f0c93809d7d28f285d43e9c4",
+		"single.txt",
+		[]File{ { "single.txt", 40000 } },
+		3,
+		[][]string{ { "http://tracker.example.org/announce" } },
+		false,
+	},
+	{
+		"multi.torrent",
+		"2c0fe67e1c7d9168b1702fc9d1166cc2603fe9ec",
+		"album",
+		[]File{
+			{ "album/cd1/01.flac", 20000 },
+			{ "album/cd2/02.flac", 30000 },
+			{ "album/empty", 0 },
+		},
+		4,
+		[][]string{
+			{ "http://a.example.org/announce", "http://b.example.org/announce" },
+			{ "udp://c.example.org:6969/announce" },
+		},
+		true,
+	},
+	{
+		// Legacy 'name' and 'path' are in a different encoding, '.utf-8'
+		// variants win.
+		"utf8.torrent",
+		"7ff0d68bb00c20e824b4bc328ccf6f95d36d84cf",
+		"Музыка",
+		[]File{ { "Музыка/Трек 1.mp3", 1000 } },
+		1,
+		[][]string{ { "http://tracker.example.org/announce" } },
+		false,
+	},
+}
+
+func TestGoldenTorrents(t *testing.T) {
+	for _, golden := range goldenTorrents {
+		info, err := Load(filepath.Join("testdata", golden.file))
+		if err != nil {
+			t.Errorf("%s: %s", golden.file, err)
+			continue
+		}
+
+		if hash := info.InfoHashString(); hash != golden.infoHash {
+			t.Errorf("%s: info-hash %s, expected %s", golden.file, hash, golden.infoHash)
+		}
+		if info.Name != golden.name {
+			t.Errorf("%s: name %q, expected %q", golden.file, info.Name, golden.name)
+		}
+		if !reflect.DeepEqual(info.Files, golden.files) {
+			t.Errorf("%s: files %v, expected %v", golden.file, info.Files, golden.files)
+		}
+		if info.PieceCount != golden.pieceCount {
+			t.Errorf("%s: %d pieces, expected %d", golden.file, info.PieceCount, golden.pieceCount)
+		}
+		if !reflect.DeepEqual(info.Trackers, golden.trackers) {
+			t.Errorf("%s: trackers %v, expected %v", golden.file, info.Trackers, golden.trackers)
+		}
+		if info.Private != golden.private {
+			t.Errorf("%s: private %v, expected %v", golden.file, info.Private, golden.private)
+		}
+	}
+}
+
+func TestPieceCountMismatch(t *testing.T) {
+	hashes := string(make([]byte, 2 * sha1.Size))
+
+	torrents := map[string]string{
+		"too few": "d4:infod6:lengthi40000e4:name1:a12:piece lengthi16384e6:pieces40:" + hashes + "ee",
+		"too many": "d4:infod6:lengthi10e4:name1:a12:piece lengthi16384e6:pieces40:" + hashes + "ee",
+	}
+
+	for name, data := range torrents {
+		if _, err := Parse([]byte(data)); err == nil {
+			t.Errorf("%s: expected piece count error", name)
+		}
+	}
+}
+
+func FuzzParse(f *testing.F) {
+	for _, golden := range goldenTorrents {
+		data, err := ioutil.ReadFile(filepath.Join("testdata", golden.file))
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(data)
+	}
+	f.Add([]byte("d4:infod6:lengthi0e4:name1:a12:piece lengthi1e6:pieces0:ee"))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		info, err := Parse(data)
+		if err != nil {
+			return
+		}
+
+		// Every piece has a hash and pieces add up to the data.
+		var total int64
+		for index := 0; index < info.PieceCount; index++ {
+			if len(info.PieceHash(index)) != sha1.Size {
+				t.Fatalf("Piece %d has no hash", index)
+			}
+
+			size := info.PieceSize(index)
+			if size <= 0 || size > info.PieceLength {
+				t.Fatalf("Piece %d has size %d", index, size)
+			}
+			total += size
+		}
+
+		if total != info.TotalSize() {
+			t.Fatalf("Pieces cover %d bytes of %d", total, info.TotalSize())
+		}
+	})
+}
@@ -0,0 +1,99 @@
+package metainfo
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+)
+
+// One of the torrent's files on disk.
+type DataFile struct {
+	Path string
+	Length int64
+	// Position of the file within the concatenated data.
+	Offset int64
+}
+
+// File on disk ended before its length in the torrent.
+type ShortFileError struct {
+	Path string
+}
+
+func (e *ShortFileError) Error() string {
+	return fmt.Sprintf("%s is shorter than expected", e.Path)
+}
+
+func (e *ShortFileError) Is(target error) bool {
+	return target == io.ErrUnexpectedEOF
+}
+
+// Reads ranges of the concatenated files, like pieces. Pieces are mostly read
+// in order, so only the last used file is kept open. Not safe for concurrent
+// use, every goroutine needs its own.
+type PieceReader struct {
+	files []DataFile
+	current int
+	handle *os.File
+}
+
+// Files have to be ordered by offset.
+func NewPieceReader(files []DataFile) *PieceReader {
+	return &PieceReader{ files, 0, nil }
+}
+
+// Fills the buffer with data starting at the offset. Returns a ShortFileError
+// if a file ends early.
+func (reader *PieceReader) ReadRange(buffer []byte, offset int64) error {
+	// First file ending after the offset.
+	first := sort.Search(len(reader.files), func(index int) bool {
+		file := reader.files[index]
+		return file.Offset + file.Length > offset
+	})
+
+	for index := first; index < len(reader.files) && len(buffer) > 0; index++ {
+		file := reader.files[index]
+		end := file.Offset + file.Length
+		if file.Length == 0 {
+			continue
+		}
+
+		if reader.handle == nil || reader.current != index {
+			reader.Close()
+
+			handle, err := os.Open(file.Path)
+			if err != nil {
+				return err
+			}
+			reader.current, reader.handle = index, handle
+		}
+
+		chunk := buffer
+		if int64(len(chunk)) > end - offset {
+			chunk = chunk[:end - offset]
+		}
+
+		if _, err := reader.handle.ReadAt(chunk, offset - file.Offset); err != nil {
+			if err == io.EOF {
+				return &ShortFileError{ file.Path }
+			}
+			return err
+		}
+
+		buffer = buffer[len(chunk):]
+		offset += int64(len(chunk))
+	}
+
+	// Range goes past the last file.
+	if len(buffer) > 0 {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+
+func (reader *PieceReader) Close() {
+	if reader.handle != nil {
+		reader.handle.Close()
+		reader.handle = nil
+	}
+}
@@ -0,0 +1,80 @@
+package metainfo
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Writes the contents as files, returns them laid out one after another.
+func writeDataFiles(t *testing.T, directory string, contents ...string) []DataFile {
+	files := make([]DataFile, len(contents))
+
+	var offset int64
+	for index, data := range contents {
+		path := filepath.Join(directory, string('a' + rune(index)))
+		if err := ioutil.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+		files[index] = DataFile{ Path: path, Length: int64(len(data)), Offset: offset }
+		offset += int64(len(data))
+	}
+	return files
+}
+
+func TestPieceReader(t *testing.T) {
+	directory, err := ioutil.TempDir("", "metainfo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(directory)
+
+	files := writeDataFiles(t, directory, "0123", "", "456", "789ab")
+	data := "0123456789ab"
+
+	reader := NewPieceReader(files)
+	defer reader.Close()
+
+	// Every range, crossing file boundaries and the empty file in any order.
+	for start := 0; start < len(data); start++ {
+		for end := start; end <= len(data); end++ {
+			buffer := make([]byte, end - start)
+			if err := reader.ReadRange(buffer, int64(start)); err != nil {
+				t.Fatalf("[%d, %d): %s", start, end, err)
+			}
+			if !bytes.Equal(buffer, []byte(data[start:end])) {
+				t.Fatalf("[%d, %d): expected %q, got %q", start, end, data[start:end], buffer)
+			}
+		}
+	}
+
+	// Range past the last file.
+	if err := reader.ReadRange(make([]byte, 4), 10); err != io.ErrUnexpectedEOF {
+		t.Errorf("Expected io.ErrUnexpectedEOF past the end, got %v", err)
+	}
+}
+
+func TestPieceReaderShortFile(t *testing.T) {
+	directory, err := ioutil.TempDir("", "metainfo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(directory)
+
+	files := writeDataFiles(t, directory, "0123", "456")
+	// Torrent expects more than the first file has.
+	files[0].Length, files[1].Offset = 6, 6
+
+	reader := NewPieceReader(files)
+	defer reader.Close()
+
+	err = reader.ReadRange(make([]byte, 8), 0)
+	var short *ShortFileError
+	if !errors.As(err, &short) || short.Path != files[0].Path || !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Expected a short file error for %s, got %v", files[0].Path, err)
+	}
+}
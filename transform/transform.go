@@ -35,6 +35,30 @@ func GeneralizeFiles(items []transmission.TorrentFile) []list.Identifiable {
 	return output
 }
 
+func GeneralizeTrackers(items []transmission.TrackerStat) []list.Identifiable {
+	output := make([]list.Identifiable, len(items))
+	for ind, item := range items {
+		output[ind] = item
+	}
+	return output
+}
+
+func GeneralizePeers(items []transmission.Peer) []list.Identifiable {
+	output := make([]list.Identifiable, len(items))
+	for ind, item := range items {
+		output[ind] = item
+	}
+	return output
+}
+
+func ToTrackerList(items []list.Identifiable) []transmission.TrackerStat {
+	output := make([]transmission.TrackerStat, len(items))
+	for ind, item := range items {
+		output[ind] = item.(transmission.TrackerStat)
+	}
+	return output
+}
+
 func ToTorrentList(items []list.Identifiable) []transmission.TorrentListItem {
 	output := make([]transmission.TorrentListItem, len(items))
 	for ind, item := range items {
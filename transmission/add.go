@@ -1,25 +1,144 @@
 package transmission
 
-import "net/http"
+import (
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"strings"
+)
 
-func AddRequest(filename string, downloadDir string, paused bool) RequestBuilder {
-	return func(conn Connection, token string)(*http.Request, error) {
-		return TRequest{
-			conn,
-			"torrent-add",
-			token,
-			map[string]interface{} { "filename": filename, "download-dir": downloadDir, "paused": paused }}.ToRequest()
+// Optional 'torrent-add' arguments. Zero values mean daemon's defaults.
+type AddOptions struct {
+	DownloadDir string
+	Paused bool
+	// File indexes. Files not mentioned are wanted, with normal priority.
+	FilesWanted []int
+	FilesUnwanted []int
+	PriorityHigh []int
+	PriorityNormal []int
+	PriorityLow []int
+	// One of TR_PRIORITY_* values.
+	BandwidthPriority int
+	PeerLimit int
+	// Requires FEATURE_LABELS.
+	Labels []string
+	// Cookies for fetching the torrent by URL, like 'name=value; name2=value2'.
+	Cookies string
+}
+
+func (options AddOptions) arguments() map[string]interface{} {
+	arguments := map[string]interface{}{ "paused": options.Paused }
+
+	if options.DownloadDir != "" {
+		arguments["download-dir"] = options.DownloadDir
+	}
+
+	lists := map[string][]int{
+		"files-wanted": options.FilesWanted,
+		"files-unwanted": options.FilesUnwanted,
+		"priority-high": options.PriorityHigh,
+		"priority-normal": options.PriorityNormal,
+		"priority-low": options.PriorityLow,
+	}
+	for key, list := range lists {
+		if len(list) > 0 {
+			arguments[key] = list
+		}
+	}
+
+	if options.BandwidthPriority != TR_PRIORITY_NORMAL {
+		arguments["bandwidthPriority"] = options.BandwidthPriority
+	}
+	if options.PeerLimit > 0 {
+		arguments["peer-limit"] = options.PeerLimit
+	}
+	if len(options.Labels) > 0 {
+		arguments["labels"] = options.Labels
+	}
+	if options.Cookies != "" {
+		arguments["cookies"] = options.Cookies
+	}
+
+	return arguments
+}
+
+// URL, magnet link or a path on the daemon's machine.
+func AddRequest(filename string, options AddOptions) RequestBuilder {
+	return func() TRequest {
+		arguments := options.arguments()
+		arguments["filename"] = filename
+		return TRequest{ "torrent-add", arguments }
 	}
 }
 
+// Contents of a .torrent file. Works regardless of where the daemon runs.
+func AddMetainfoRequest(metainfo []byte, options AddOptions) RequestBuilder {
+	encoded := base64.StdEncoding.EncodeToString(metainfo)
+	return func() TRequest {
+		arguments := options.arguments()
+		arguments["metainfo"] = encoded
+		return TRequest{ "torrent-add", arguments }
+	}
+}
+
+// Local .torrent files are uploaded, since the daemon might not see the
+// client's filesystem. Everything else is passed to the daemon as is.
+func addRequestFor(source string, options AddOptions) (RequestBuilder, error) {
+	path, ok := LocalTorrentPath(source)
+	if !ok {
+		return AddRequest(source, options), nil
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read torrent file: %s", err)
+	}
+
+	return AddMetainfoRequest(data, options), nil
+}
+
+// Path of an existing local file, given either as a path or a 'file://' URL.
+func LocalTorrentPath(source string) (string, bool) {
+	path := source
+	if parsed, err := url.Parse(source); err == nil && parsed.Scheme != "" {
+		// Windows drive letters look like schemes too.
+		if parsed.Scheme != "file" && len(parsed.Scheme) > 1 {
+			return "", false
+		}
+		if parsed.Scheme == "file" {
+			path = parsed.Path
+		}
+	}
+
+	if strings.TrimSpace(path) == "" {
+		return "", false
+	}
+
+	info, err := os.Stat(path)
+	if err != nil || !info.Mode().IsRegular() {
+		return "", false
+	}
+	return path, true
+}
+
 type TorrentAddedInfo struct {
 	HashString string `json:"hashString"`
 	Id int						`json:"id"`
 	Name string				`json:"name"`
 }
 
+// Outcome of adding a torrent. When the daemon already has it, Torrent
+// describes the existing one and nothing is added.
+type AddResult struct {
+	Torrent TorrentAddedInfo
+	Duplicate bool
+}
+
 type TorrentAddResponseArguments struct {
-	Torrent *TorrentAddedInfo `json:"torrent-added"`
+	Torrent *TorrentAddedInfo		`json:"torrent-added"`
+	Duplicate *TorrentAddedInfo `json:"torrent-duplicate"`
 }
 
 type TorrentAddResponse struct {
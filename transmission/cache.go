@@ -0,0 +1,95 @@
+package transmission
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// How often the whole list is re-downloaded, in case something was missed.
+	LIST_RESYNC_INTERVAL = 5 * time.Minute
+	// Daemon's window for 'recently-active'. Longer gaps between polls
+	// require a full sync.
+	RECENTLY_ACTIVE_WINDOW = 60 * time.Second
+)
+
+// Local copy of the torrent list. Full list is downloaded only on first call,
+// after reconnects and every LIST_RESYNC_INTERVAL; other calls fetch only
+// recently active torrents and merge them in.
+type listCache struct {
+	lock sync.Mutex
+	torrents map[int]TorrentListItem
+	session int
+	lastSync time.Time
+	lastPoll time.Time
+}
+
+func (cache *listCache) update(ctx context.Context, client *Client) (*[]TorrentListItem, error) {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+
+	now := client.now()
+	session := client.session()
+
+	// Client that isn't connected yet might be reconnecting, and the session
+	// only changes once it does.
+	if cache.torrents == nil ||
+		cache.session != session ||
+		!client.IsConnected() ||
+		now.Sub(cache.lastSync) >= LIST_RESYNC_INTERVAL ||
+		now.Sub(cache.lastPoll) >= RECENTLY_ACTIVE_WINDOW {
+		torrents, err := client.TorrentGetContext(ctx, nil, LIST_FIELDS...)
+		if err != nil {
+			return nil, err
+		}
+
+		cache.torrents = make(map[int]TorrentListItem, len(torrents))
+		for _, torrent := range torrents {
+			cache.torrents[torrent.Id] = NewTorrentListItem(torrent)
+		}
+
+		// Session is read after the request, since the request itself might
+		// have been the one to reconnect.
+		cache.session = client.session()
+		cache.lastSync, cache.lastPoll = now, now
+	} else {
+		torrents, removed, err := client.RecentlyActiveContext(ctx, LIST_FIELDS...)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, torrent := range torrents {
+			cache.torrents[torrent.Id] = NewTorrentListItem(torrent)
+		}
+		for _, id := range removed {
+			delete(cache.torrents, id)
+		}
+
+		cache.lastPoll = now
+	}
+
+	return cache.list(), nil
+}
+
+func (client *Client) now() time.Time {
+	if client.Clock != nil {
+		return client.Clock()
+	}
+	return time.Now()
+}
+
+// Cached torrents ordered by id, the way the daemon returns them.
+func (cache *listCache) list() *[]TorrentListItem {
+	items := make([]TorrentListItem, 0, len(cache.torrents))
+	for _, item := range cache.torrents {
+		items = append(items, item)
+	}
+
+	sort.Slice(items, func(l, r int) bool {
+		return items[l].TorrentId < items[r].TorrentId
+	})
+
+	return &items
+}
@@ -0,0 +1,161 @@
+package transmission
+
+import (
+	"context"
+	"fmt"
+)
+
+/* Features */
+
+type Feature int
+
+const (
+	FEATURE_LABELS Feature = iota
+	FEATURE_TABLE_FORMAT
+	FEATURE_TRACKER_LIST
+	FEATURE_JSON_RPC
+)
+
+// Highest RPC version of the legacy protocol the client knows about.
+const LEGACY_RPC_VERSION = 17
+
+// RPC version each feature appeared in.
+var featureVersions = map[Feature]int{
+	FEATURE_LABELS: 16,
+	FEATURE_TABLE_FORMAT: 16,
+	FEATURE_TRACKER_LIST: 17,
+	FEATURE_JSON_RPC: 18,
+}
+
+var featureNames = map[Feature]string{
+	FEATURE_LABELS: "labels",
+	FEATURE_TABLE_FORMAT: "table format",
+	FEATURE_TRACKER_LIST: "tracker list",
+	FEATURE_JSON_RPC: "JSON-RPC",
+}
+
+func (feature Feature) String() string {
+	return featureNames[feature]
+}
+
+// RPC version the feature appeared in.
+func (feature Feature) Version() int {
+	return featureVersions[feature]
+}
+
+/* Capabilities */
+
+type Capabilities struct {
+	RPCVersion int				`json:"rpc-version"`
+	RPCVersionMinimum int `json:"rpc-version-minimum"`
+	Version string				`json:"version"`
+}
+
+func (capabilities Capabilities) Supports(feature Feature) bool {
+	return capabilities.RPCVersion >= feature.Version()
+}
+
+// Whether the daemon still accepts requests in the legacy protocol.
+func (capabilities Capabilities) Compatible() bool {
+	return capabilities.RPCVersionMinimum <= LEGACY_RPC_VERSION
+}
+
+func (capabilities Capabilities) String() string {
+	return fmt.Sprintf("Transmission %s, RPC version %d", capabilities.Version, capabilities.RPCVersion)
+}
+
+func CapabilitiesRequest() TRequest {
+	return TRequest{
+		"session-get",
+		map[string]interface{}{
+			"fields": []string{
+				"rpc-version",
+				"rpc-version-minimum",
+				"version"}}}
+}
+
+type CapabilitiesResponse struct {
+	ResultValue string						 `json:"result"`
+	TagValue string								 `json:"tag"`
+	ArgumentsValue *Capabilities `json:"arguments"`
+}
+
+func (response CapabilitiesResponse) Result() string {
+	return response.ResultValue
+}
+
+func (response CapabilitiesResponse) Tag() string {
+	return response.TagValue
+}
+
+func (response CapabilitiesResponse) Arguments() interface{} {
+	return response.ArgumentsValue
+}
+
+/* Client */
+
+// Daemon's capabilities, fetched once per connection.
+func (client *Client) Capabilities() (*Capabilities, error) {
+	return client.CapabilitiesContext(context.Background())
+}
+
+func (client *Client) CapabilitiesContext(ctx context.Context) (*Capabilities, error) {
+	if capabilities := client.cachedCapabilities(); capabilities != nil {
+		return capabilities, nil
+	}
+
+	var response CapabilitiesResponse
+	if err := client.performJson(ctx, CapabilitiesRequest, &response); err != nil {
+		return nil, err
+	}
+
+	capabilities := response.Arguments().(*Capabilities)
+	if capabilities == nil {
+		return nil, &DecodeError{"session-get", fmt.Errorf("no arguments in response")}
+	}
+
+	client.lock.Lock()
+	defer client.lock.Unlock()
+
+	client.capabilities, client.capabilitiesSession = capabilities, client.sessions
+	return capabilities, nil
+}
+
+// Whether the connected daemon supports the feature. Never blocks: until
+// capabilities are fetched for the current connection it reports false.
+func (client *Client) Supports(feature Feature) bool {
+	capabilities := client.cachedCapabilities()
+	return capabilities != nil && capabilities.Supports(feature)
+}
+
+// Returns UnsupportedError if the daemon doesn't have the feature.
+func (client *Client) require(ctx context.Context, feature Feature) error {
+	capabilities, err := client.CapabilitiesContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	if !capabilities.Supports(feature) {
+		return &UnsupportedError{feature, capabilities.RPCVersion}
+	}
+	return nil
+}
+
+func (client *Client) cachedCapabilities() *Capabilities {
+	client.lock.RLock()
+	defer client.lock.RUnlock()
+
+	if client.sessions == 0 || client.capabilitiesSession != client.sessions {
+		return nil
+	}
+	return client.capabilities
+}
+
+// Builder for 'torrent-get', using the table format if the daemon has it.
+func (client *Client) torrentGetBuilder(ctx context.Context, builder RequestBuilder) RequestBuilder {
+	capabilities, err := client.CapabilitiesContext(ctx)
+	if err == nil && capabilities.Supports(FEATURE_TABLE_FORMAT) {
+		return WithTableFormat(builder)
+	}
+	return builder
+}
@@ -1,118 +1,199 @@
 package transmission
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"net/http"
-	"fmt"
+	"net/url"
 	"io/ioutil"
-	"encoding/json"
+	"sync"
+	"time"
 )
 
+const DEFAULT_TIMEOUT = 10 * time.Second
+
 type Client struct {
-	Host string
-	Port int32
-	Connected bool
+	Connection Connection
+	// Shared between all requests to reuse keep-alive connections.
+	HTTPClient *http.Client
+	// Deadline for a single request, including the session ID replay.
+	// Zero means no deadline besides the one from the caller's context.
+	Timeout time.Duration
+	// Wire protocol. Nil means automatic: JSON-RPC if the daemon supports it,
+	// legacy otherwise.
+	Transport Transport
+	// Current time for the list cache's sync intervals. Nil means time.Now.
+	Clock func() time.Time
+
+	// Guards session state, since windows' workers use the client concurrently.
+	lock sync.RWMutex
 	token string
+	status ConnectionStatus
+	// Incremented every time connection is (re)established.
+	sessions int
+	// Daemon's capabilities and the connection they were fetched for.
+	capabilities *Capabilities
+	capabilitiesSession int
+
+	cache listCache
+	jsonrpc JSONRPCTransport
 }
 
-func NewClient(host string, port int32) *Client {
-	return &Client{ host, port, false, "" }
-}
-
-func (client *Client) refresh() error {
-	req, err := RefreshRequest(Connection{client.Host, client.Port}).ToRequest()
+func NewClient(connection Connection) (*Client, error) {
+	httpClient, err := connection.httpClient()
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 
-	httpClient := &http.Client{}
-	response, err := httpClient.Do(req)
+	return &Client{
+		Connection: connection,
+		HTTPClient: httpClient,
+		Timeout: DEFAULT_TIMEOUT,
+	}, nil
+}
+
+func (client *Client) sessionToken() string {
+	client.lock.RLock()
+	defer client.lock.RUnlock()
+	return client.token
+}
+
+func (client *Client) setSessionToken(token string) {
+	client.lock.Lock()
+	defer client.lock.Unlock()
+	client.token = token
+}
 
+func (client *Client) send(ctx context.Context, body []byte, token string) (*http.Response, error) {
+	req, err := http.NewRequest("POST", client.Connection.Url.String(), bytes.NewReader(body))
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	token := response.Header["X-Transmission-Session-Id"][0]
-	if token == "" {
-		return fmt.Errorf("Failed to authenticate: couldn't receive session ID token.")
+	if token != "" {
+		req.Header.Add(SESSION_ID_HEADER, token)
 	}
 
-	client.token = token
-	client.Connected = true
-	return nil
-}
-
-func (client *Client) perform(builder func(Connection, string)(*http.Request, error)) ([]byte, error) {
-	var err error
-	if client.token == "" {
-		err = client.refresh()
+	// Daemons with 'rpc-authentication-required' expect Basic auth on every request.
+	if client.Connection.Username != "" {
+		req.SetBasicAuth(client.Connection.Username, client.Connection.Password)
 	}
 
+	response, err := client.HTTPClient.Do(req.WithContext(ctx))
 	if err != nil {
-		return nil, err
+		// Cancellation is caller's decision, not a connectivity problem.
+		if errors.Is(err, context.Canceled) {
+			return nil, err
+		}
+
+		// URL is already part of ConnectionError's message.
+		var urlError *url.Error
+		if errors.As(err, &urlError) {
+			err = urlError.Err
+		}
+		return nil, &ConnectionError{client.Connection.String(), err}
 	}
 
-	req, err := builder(Connection{client.Host, client.Port}, client.token)
+	return response, nil
+}
 
-	if err != nil {
+func (client *Client) perform(ctx context.Context, body []byte) ([]byte, error) {
+	if err := client.beginRequest(); err != nil {
 		return nil, err
 	}
 
-	httpClient := &http.Client{}
-	response, err := httpClient.Do(req)
+	data, err := client.exchange(ctx, body)
+	if err == nil {
+		client.markConnected()
+	} else if isConnectionFailure(err) {
+		client.markFailed(err)
+	}
 
+	return data, err
+}
+
+func (client *Client) exchange(ctx context.Context, body []byte) ([]byte, error) {
+	if client.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, client.Timeout)
+		defer cancel()
+	}
+
+	response, err := client.send(ctx, body, client.sessionToken())
 	if err != nil {
 		return nil, err
 	}
 
-
-	if header, present := response.Header["X-Transmission-Session-Id"]; present {
-		client.token = header[0]
-	} else {
-		err = fmt.Errorf("No token present")
+	// Session ID is either missing or has been rotated by the daemon.
+	// 409 response carries a fresh one, so the request is replayed once with it.
+	if response.StatusCode == http.StatusConflict {
+		response.Body.Close()
+
+		token := response.Header.Get(SESSION_ID_HEADER)
+		if token == "" {
+			return nil, ErrSessionConflict
+		}
+		client.setSessionToken(token)
+
+		response, err = client.send(ctx, body, token)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	if (response.StatusCode != 200) {
-		return nil, fmt.Errorf("Bad response code: %d", response.StatusCode)
+	defer response.Body.Close()
+
+	switch response.StatusCode {
+	case http.StatusOK:
+		break
+	case http.StatusUnauthorized:
+		return nil, &AuthError{client.Connection.Username}
+	case http.StatusConflict:
+		return nil, ErrSessionConflict
+	default:
+		return nil, &HTTPError{response.StatusCode}
 	}
 
-	defer response.Body.Close()
-	body, err := ioutil.ReadAll(response.Body)
+	data, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, &ConnectionError{client.Connection.String(), err}
+	}
 
-	return body, err
+	return data, nil
 }
 
-func (client *Client) performJson(
-	builder func(Connection, string)(*http.Request, error),
-	response TResponse) error {
-	body, err := client.perform(builder)
+func (client *Client) performJson(ctx context.Context, builder RequestBuilder, response TResponse) error {
+	request := builder()
+	transport := client.transport()
 
+	body, err := transport.Encode(request)
 	if err != nil {
 		return err
 	}
 
-	jsonErr := json.Unmarshal(body, &response)
-	if jsonErr != nil {
-		return fmt.Errorf("Error: %s", jsonErr)
-	}
-
-	if (response.Result() != "success") {
-		return fmt.Errorf("Error: %s", response.Result())
+	data, err := client.perform(ctx, body)
+	if err != nil {
+		return err
 	}
 
-	return nil
+	return transport.Decode(request.Method, data, response)
 }
 
-func (client *Client) performWithoutData(builder func(Connection, string)(*http.Request, error)) error {
+func (client *Client) performWithoutData(ctx context.Context, builder RequestBuilder) error {
 	var response GenericResponse
-	err := client.performJson(builder, &response)
+	return client.performJson(ctx, builder, &response)
+}
 
-	if err != nil {
-		return err
+// Until capabilities are known for the current connection, legacy protocol is
+// used, since every daemon understands it.
+func (client *Client) transport() Transport {
+	if client.Transport != nil {
+		return client.Transport
 	}
 
-	if (response.Result() != "success") {
-		return fmt.Errorf("Error: %s", response.Result())
+	if client.Supports(FEATURE_JSON_RPC) {
+		return &client.jsonrpc
 	}
-
-	return nil
+	return LegacyTransport{}
 }
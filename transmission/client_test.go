@@ -0,0 +1,144 @@
+package transmission
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+// Daemon stand-in answering with a handler, counting requests.
+type testDaemon struct {
+	lock sync.Mutex
+	requests int
+	tokens []string
+}
+
+func (daemon *testDaemon) serve(
+	t *testing.T,
+	handler func(request int, token string, writer http.ResponseWriter),
+) (*Client, *httptest.Server) {
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, req *http.Request) {
+		daemon.lock.Lock()
+		daemon.requests += 1
+		request, token := daemon.requests, req.Header.Get(SESSION_ID_HEADER)
+		daemon.tokens = append(daemon.tokens, token)
+		daemon.lock.Unlock()
+
+		handler(request, token, writer)
+	}))
+
+	connection, err := ParseConnection(server.URL)
+	if err != nil {
+		server.Close()
+		t.Fatal(err)
+	}
+
+	client, err := NewClient(connection)
+	if err != nil {
+		server.Close()
+		t.Fatal(err)
+	}
+	return client, server
+}
+
+func conflict(writer http.ResponseWriter, token string) {
+	writer.Header().Set(SESSION_ID_HEADER, token)
+	writer.WriteHeader(http.StatusConflict)
+}
+
+func TestSessionTokenRotation(t *testing.T) {
+	daemon := &testDaemon{}
+	current := "first"
+	client, server := daemon.serve(t, func(request int, token string, writer http.ResponseWriter) {
+		if token != current {
+			conflict(writer, current)
+			return
+		}
+		fmt.Fprint(writer, `{"result":"success"}`)
+	})
+	defer server.Close()
+
+	// No token yet: 409, then a single replay with the issued one.
+	if _, err := client.exchange(context.Background(), []byte(`{}`)); err != nil {
+		t.Fatal(err)
+	}
+	if daemon.requests != 2 || daemon.tokens[1] != "first" {
+		t.Fatalf("Expected one replay with the new token, got tokens %q", daemon.tokens)
+	}
+
+	// Known token is reused without a handshake.
+	if _, err := client.exchange(context.Background(), []byte(`{}`)); err != nil {
+		t.Fatal(err)
+	}
+	if daemon.requests != 3 {
+		t.Fatalf("Expected no handshake with a valid token, got %d requests", daemon.requests)
+	}
+
+	// Daemon rotates the token: replayed exactly once with the new one.
+	current = "second"
+	if _, err := client.exchange(context.Background(), []byte(`{}`)); err != nil {
+		t.Fatal(err)
+	}
+	if daemon.requests != 5 || daemon.tokens[3] != "first" || daemon.tokens[4] != "second" {
+		t.Fatalf("Expected a replay with the rotated token, got tokens %q", daemon.tokens)
+	}
+	if token := client.sessionToken(); token != "second" {
+		t.Fatalf("Expected client to keep the rotated token, got %q", token)
+	}
+}
+
+func TestSessionConflictTwiceInARow(t *testing.T) {
+	daemon := &testDaemon{}
+	client, server := daemon.serve(t, func(request int, token string, writer http.ResponseWriter) {
+		// New token every time, none of them accepted.
+		conflict(writer, fmt.Sprintf("token-%d", request))
+	})
+	defer server.Close()
+
+	_, err := client.exchange(context.Background(), []byte(`{}`))
+	if !errors.Is(err, ErrSessionConflict) {
+		t.Fatalf("Expected ErrSessionConflict, got %v", err)
+	}
+	if daemon.requests != 2 {
+		t.Fatalf("Expected the request to be replayed once, got %d requests", daemon.requests)
+	}
+}
+
+func TestSessionConflictWithoutToken(t *testing.T) {
+	daemon := &testDaemon{}
+	client, server := daemon.serve(t, func(request int, token string, writer http.ResponseWriter) {
+		writer.WriteHeader(http.StatusConflict)
+	})
+	defer server.Close()
+
+	_, err := client.exchange(context.Background(), []byte(`{}`))
+	if !errors.Is(err, ErrSessionConflict) {
+		t.Fatalf("Expected ErrSessionConflict, got %v", err)
+	}
+	if daemon.requests != 1 {
+		t.Fatalf("Expected no replay without a token, got %d requests", daemon.requests)
+	}
+}
+
+func TestUnauthorized(t *testing.T) {
+	daemon := &testDaemon{}
+	client, server := daemon.serve(t, func(request int, token string, writer http.ResponseWriter) {
+		writer.WriteHeader(http.StatusUnauthorized)
+	})
+	defer server.Close()
+	client.Connection.Username, client.Connection.Password = "user", "wrong"
+
+	_, err := client.exchange(context.Background(), []byte(`{}`))
+	if !errors.Is(err, ErrUnauthorized) {
+		t.Fatalf("Expected ErrUnauthorized, got %v", err)
+	}
+
+	var authError *AuthError
+	if !errors.As(err, &authError) || authError.Username != "user" {
+		t.Fatalf("Expected AuthError for 'user', got %v", err)
+	}
+}
@@ -0,0 +1,138 @@
+package transmission
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const DEFAULT_RPC_PATH = "/transmission/rpc/"
+
+/* TLS */
+
+type TLSOptions struct {
+	// PEM bundle with additional CAs to trust.
+	CAFile string
+	// Client certificate and its key. Key may be omitted if it's bundled
+	// into the certificate file.
+	CertFile string
+	KeyFile string
+	// Skip server certificate verification.
+	InsecureSkipVerify bool
+}
+
+func (options TLSOptions) Config() (*tls.Config, error) {
+	config := &tls.Config{
+		InsecureSkipVerify: options.InsecureSkipVerify,
+	}
+
+	if options.CAFile != "" {
+		data, err := ioutil.ReadFile(options.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read CA bundle: %s", err)
+		}
+
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("No certificates found in %s", options.CAFile)
+		}
+		config.RootCAs = pool
+	}
+
+	if options.CertFile != "" {
+		keyFile := options.KeyFile
+		if keyFile == "" {
+			keyFile = options.CertFile
+		}
+
+		cert, err := tls.LoadX509KeyPair(options.CertFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to load client certificate: %s", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	return config, nil
+}
+
+/* Connection */
+
+type Connection struct {
+	Url *url.URL
+	Username string
+	Password string
+	TLS TLSOptions
+}
+
+// Builds a plain HTTP connection to the default RPC endpoint.
+// IPv6 literals are accepted with or without brackets.
+func NewConnection(host string, port int32) Connection {
+	host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+
+	return Connection{
+		Url: &url.URL{
+			Scheme: "http",
+			Host: net.JoinHostPort(host, fmt.Sprintf("%d", port)),
+			Path: DEFAULT_RPC_PATH,
+		},
+	}
+}
+
+// Builds a connection from a full RPC URL, like 'https://[::1]:443/custom/rpc/'.
+// Credentials in the URL's user info part are moved to Username/Password.
+// Empty path is replaced with the default RPC path.
+func ParseConnection(rawurl string) (Connection, error) {
+	parsed, err := url.Parse(rawurl)
+	if err != nil {
+		return Connection{}, fmt.Errorf("Invalid URL: %s", err)
+	}
+
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return Connection{}, fmt.Errorf("Unsupported URL scheme: '%s'", parsed.Scheme)
+	}
+
+	if parsed.Hostname() == "" {
+		return Connection{}, fmt.Errorf("URL has no host: %s", rawurl)
+	}
+
+	if parsed.Path == "" || parsed.Path == "/" {
+		parsed.Path = DEFAULT_RPC_PATH
+	}
+
+	connection := Connection{}
+	if parsed.User != nil {
+		connection.Username = parsed.User.Username()
+		connection.Password, _ = parsed.User.Password()
+		parsed.User = nil
+	}
+
+	connection.Url = parsed
+	return connection, nil
+}
+
+func (conn Connection) String() string {
+	return conn.Url.String()
+}
+
+func (conn Connection) httpClient() (*http.Client, error) {
+	config, err := conn.TLS.Config()
+	if err != nil {
+		return nil, err
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = config
+	// List and session workers poll the same host concurrently.
+	transport.MaxIdleConnsPerHost = 4
+
+	return &http.Client{ Transport: transport }, nil
+}
@@ -1,32 +1,31 @@
 package transmission
 
-import "net/http"
+// Fields needed for the details screen's header.
+var DETAILS_FIELDS = []string{
+	"error",
+	"errorString",
+	"eta",
+	"id",
+	"leftUntilDone",
+	"name",
+	"rateDownload",
+	"rateUpload",
+	"sizeWhenDone",
+	"status",
+	"uploadRatio",
+	"downloadLimit",
+	"downloadLimited",
+	"uploadLimit",
+	"uploadLimited",
+	"downloadDir"}
 
-func DetailsRequest(id int, fields []string) RequestBuilder {
-	return func(conn Connection, token string) (*http.Request, error) {
-		return TRequest{
-			conn,
-			"torrent-get",
-			token,
-			map[string]interface{} {
-				"ids": []int{ id },
-				"fields": fields,
-			},
-		}.ToRequest()
-	}
-}
-
-type TorrentFileInternal struct {
-	BytesCompleted int64 `json:"bytesCompleted"`
-	Length int64				 `json:"length"`
-	Name string					 `json:"name"`
-}
-
-type TorrentFileStatsInternal struct {
-	BytesCompleted int64 `json:"bytesCompleted"`
-	Wanted bool					 `json:"wanted"`
-	Priority int				 `json:"priority"`
-}
+// Fields of the details screen's tabs. Only the visible tab's are fetched,
+// since lists of files and peers can be long.
+var (
+	DETAILS_FILES_FIELDS = []string{ "files", "fileStats" }
+	DETAILS_TRACKERS_FIELDS = []string{ "trackerStats" }
+	DETAILS_PEERS_FIELDS = []string{ "peers", "peersFrom" }
+)
 
 type TorrentFile struct {
 	Number int
@@ -37,25 +36,6 @@ type TorrentFile struct {
 	Priority int
 }
 
-type TorrentDetailsInternal struct {
-	Id int																`json:"id"`
-	Name string														`json:"name"`
-	UploadSpeed float32										`json:"rateUpload"`
-	DownloadSpeed float32									`json:"rateDownload"`
-	Ratio float32													`json:"uploadRatio"`
-	Eta int32															`json:"eta"`
-	SizeWhenDone int64										`json:"sizeWhenDone"`
-	LeftUntilDone int64										`json:"leftUntilDone"`
-	Status int8														`json:"status"`
-	DownloadLimit int											`json:"downloadLimit"`
-	DownloadLimited bool									`json:"downloadLimited"`
-	UploadLimit int												`json:"uploadLimit"`
-	UploadLimited bool										`json:"uploadLimited"`
-	DownloadDir string										`json:"downloadDir"`
-	Files *[]TorrentFileInternal					`json:"files"`
-	FileStats *[]TorrentFileStatsInternal `json:"fileStats"`
-}
-
 type TorrentDetails struct {
 	Id int
 	Name string
@@ -72,27 +52,46 @@ type TorrentDetails struct {
 	UploadLimited bool
 	DownloadDir string
 	Files []TorrentFile
+	Trackers []TrackerStat
+	Peers []Peer
+	PeersFrom PeersFrom
 }
 
-type TorrentDetailsResponseArguments struct {
-	Torrents *[]TorrentDetailsInternal `json:"torrents"`
-}
+func NewTorrentDetails(torrent Torrent) TorrentDetails {
+	files := make([]TorrentFile, len(torrent.Files))
+	for index, file := range torrent.Files {
+		files[index] = TorrentFile{
+			index,
+			file.BytesCompleted,
+			file.Length,
+			file.Name,
+			false,
+			TR_PRIORITY_NORMAL}
 
-type TorrentDetailsResponse struct {
-	ResultValue string														 `json:"result"`
-	TagValue string																 `json:"tag"`
-	ArgumentsValue TorrentDetailsResponseArguments `json:"arguments"`
-}
-
-func (response TorrentDetailsResponse) Result() string {
-	return response.ResultValue
-}
-
-func (response TorrentDetailsResponse) Tag() string {
-	return response.TagValue
-}
+		if index < len(torrent.FileStats) {
+			files[index].Wanted = torrent.FileStats[index].Wanted
+			files[index].Priority = torrent.FileStats[index].Priority
+		}
+	}
 
-func (response TorrentDetailsResponse) Arguments() interface{} {
-	return response.ArgumentsValue
+	return TorrentDetails{
+		torrent.Id,
+		torrent.Name,
+		float32(torrent.RateUpload),
+		float32(torrent.RateDownload),
+		float32(torrent.UploadRatio),
+		int32(torrent.Eta),
+		torrent.SizeWhenDone,
+		torrent.LeftUntilDone,
+		int8(torrent.Status),
+		torrent.DownloadLimit,
+		torrent.DownloadLimited,
+		torrent.UploadLimit,
+		torrent.UploadLimited,
+		torrent.DownloadDir,
+		files,
+		torrent.TrackerStats,
+		torrent.Peers,
+		torrent.PeersFrom,
+	}
 }
-
@@ -0,0 +1,128 @@
+package transmission
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Sentinel errors. Use errors.Is to check for them, since the actual errors
+// returned by the client usually carry more context.
+var (
+	// Daemon requires credentials, or rejected the provided ones.
+	ErrUnauthorized = errors.New("Authentication failed")
+	// Daemon couldn't be reached: connection refused, DNS failure, timeout, etc.
+	ErrUnreachable = errors.New("Daemon is unreachable")
+	// Daemon rejected the session ID it had just issued.
+	ErrSessionConflict = errors.New("Session ID was rejected twice in a row")
+	// Client is waiting before reconnecting to the daemon.
+	ErrOffline = errors.New("Client is offline")
+	// Connected daemon is too old for the requested feature.
+	ErrUnsupported = errors.New("Not supported by the daemon")
+)
+
+// Daemon replied with HTTP 401.
+type AuthError struct {
+	Username string
+}
+
+func (e *AuthError) Error() string {
+	if e.Username == "" {
+		return "Authentication required: daemon expects a username and password (-u user:password)"
+	}
+	return fmt.Sprintf("Authentication failed for user '%s': check username and password", e.Username)
+}
+
+func (e *AuthError) Is(target error) bool {
+	return target == ErrUnauthorized
+}
+
+// Request didn't reach the daemon or the daemon didn't respond.
+type ConnectionError struct {
+	Url string
+	Err error
+}
+
+func (e *ConnectionError) Error() string {
+	return fmt.Sprintf("Can't reach daemon at %s: %s", e.Url, e.Err)
+}
+
+func (e *ConnectionError) Unwrap() error {
+	return e.Err
+}
+
+func (e *ConnectionError) Is(target error) bool {
+	return target == ErrUnreachable
+}
+
+// Daemon replied with unexpected HTTP status code.
+type HTTPError struct {
+	StatusCode int
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("Bad response code: %d", e.StatusCode)
+}
+
+// Daemon processed the request, but the call itself failed.
+// Result holds daemon's 'result' string, like "duplicate torrent" or "invalid argument".
+type RPCError struct {
+	Method string
+	Result string
+}
+
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("Error: %s (%s)", e.Result, e.Method)
+}
+
+// Daemon's response couldn't be decoded.
+type DecodeError struct {
+	Method string
+	Err error
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("Error: failed to decode '%s' response: %s", e.Method, e.Err)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// Request wasn't sent, because the client is waiting out the reconnect delay.
+// Unwraps to the error that caused the disconnect.
+type OfflineError struct {
+	LastError error
+	NextRetry time.Time
+}
+
+func (e *OfflineError) Error() string {
+	if e.LastError == nil {
+		return ErrOffline.Error()
+	}
+	return e.LastError.Error()
+}
+
+func (e *OfflineError) Unwrap() error {
+	return e.LastError
+}
+
+func (e *OfflineError) Is(target error) bool {
+	return target == ErrOffline
+}
+
+// Request needs a feature the connected daemon doesn't have.
+type UnsupportedError struct {
+	Feature Feature
+	RPCVersion int
+}
+
+func (e *UnsupportedError) Error() string {
+	return fmt.Sprintf(
+		"Daemon doesn't support %s (RPC version %d, needs %d)",
+		e.Feature, e.RPCVersion, e.Feature.Version())
+}
+
+func (e *UnsupportedError) Is(target error) bool {
+	return target == ErrUnsupported
+}
@@ -1,14 +1,10 @@
 package transmission
 
-import "net/http"
-
 func ExitRequest() RequestBuilder {
-	return func(conn Connection, token string)(*http.Request, error) {
+	return func() TRequest {
 		return TRequest{
-			conn,
 			"session-close",
-			token,
-			nil}.ToRequest()
+			nil}
 	}
 }
 
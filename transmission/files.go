@@ -1,7 +1,5 @@
 package transmission
 
-import "net/http"
-
 func SetPriorityRequest(id int, files []int, priority int) RequestBuilder {
 	var priorityValue string
 	switch priority {
@@ -13,14 +11,12 @@ func SetPriorityRequest(id int, files []int, priority int) RequestBuilder {
 		priorityValue = "priority-low"
 	}
 
-	return func(conn Connection, token string) (*http.Request, error) {
+	return func() TRequest {
 		return TRequest{
-			conn,
 			"torrent-set",
-			token,
 			map[string]interface{}{
 				"ids": []int{ id },
-				priorityValue: files}}.ToRequest()
+				priorityValue: files}}
 	}
 }
 
@@ -33,13 +29,11 @@ func SetWantedRequest(id int, files []int, wanted bool) RequestBuilder {
 		wantedValue = "files-unwanted"
 	}
 
-	return func(conn Connection, token string) (*http.Request, error) {
+	return func() TRequest {
 		return TRequest{
-			conn,
 			"torrent-set",
-			token,
 			map[string]interface{}{
 				"ids": []int{ id },
-				wantedValue: files}}.ToRequest()
+				wantedValue: files}}
 	}
 }
@@ -1,21 +1,17 @@
 package transmission
 
-import "net/http"
-
 func SetGlobalUploadLimitRequest(value int) RequestBuilder {
 	var limited bool
 	if value > 0 {
 		limited = true
 	}
 
-	return func(conn Connection, token string) (*http.Request, error) {
+	return func() TRequest {
 		return TRequest{
-			conn,
 			"session-set",
-			token,
 			map[string]interface{}{
 				"speed-limit-up": value,
-				"speed-limit-up-enabled": limited}}.ToRequest()
+				"speed-limit-up-enabled": limited}}
 	}
 }
 
@@ -25,14 +21,12 @@ func SetGlobalDownloadLimitRequest(value int) RequestBuilder {
 		limited = true
 	}
 
-	return func(conn Connection, token string) (*http.Request, error) {
+	return func() TRequest {
 		return TRequest{
-			conn,
 			"session-set",
-			token,
 			map[string]interface{}{
 				"speed-limit-down": value,
-				"speed-limit-down-enabled": limited}}.ToRequest()
+				"speed-limit-down-enabled": limited}}
 	}
 }
 
@@ -44,12 +38,10 @@ func UpdateActiveRequest(active bool, ids []int) RequestBuilder {
 		command = "torrent-stop"
 	}
 
-	return func(conn Connection, token string) (*http.Request, error) {
+	return func() TRequest {
 		return TRequest{
-			conn,
 			command,
-			token,
 			map[string]interface{}{
-				"ids": ids}}.ToRequest()
+				"ids": ids}}
 	}
 }
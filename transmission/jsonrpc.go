@@ -0,0 +1,307 @@
+package transmission
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"unicode"
+)
+
+/* JSON-RPC 2.0 */
+
+// Protocol of Transmission 4.1+: '{jsonrpc, method, params, id}' requests,
+// '{jsonrpc, result, id}' or '{jsonrpc, error, id}' responses. Method and
+// field names are in snake_case.
+type JSONRPCTransport struct {
+	lastId int64
+}
+
+type jsonrpcRequest struct {
+	Version string			`json:"jsonrpc"`
+	Method string				`json:"method"`
+	Params interface{}	`json:"params,omitempty"`
+	Id int64						`json:"id"`
+}
+
+type jsonrpcError struct {
+	Code int						`json:"code"`
+	Message string			`json:"message"`
+	Data json.RawMessage `json:"data"`
+}
+
+type jsonrpcResponse struct {
+	Result json.RawMessage `json:"result"`
+	Error *jsonrpcError		 `json:"error"`
+	Id int64							 `json:"id"`
+}
+
+func (transport *JSONRPCTransport) Encode(request TRequest) ([]byte, error) {
+	var params interface{}
+	if request.Arguments != nil {
+		generic, err := toGeneric(request.Arguments)
+		if err != nil {
+			return nil, err
+		}
+		params = snakeCaseArguments(generic)
+	}
+
+	return json.Marshal(jsonrpcRequest{
+		"2.0",
+		SnakeCase(request.Method),
+		params,
+		atomic.AddInt64(&transport.lastId, 1),
+	})
+}
+
+func (transport *JSONRPCTransport) Decode(method string, body []byte, response TResponse) error {
+	var envelope jsonrpcResponse
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return &DecodeError{method, err}
+	}
+
+	if envelope.Error != nil {
+		return &RPCError{method, envelope.Error.describe()}
+	}
+
+	// Rename fields back and decode as a legacy response, so every response
+	// type works with both transports.
+	var result interface{}
+	if len(envelope.Result) > 0 {
+		var err error
+		result, err = decodeGeneric(envelope.Result)
+		if err != nil {
+			return &DecodeError{method, err}
+		}
+	}
+
+	legacy, err := json.Marshal(map[string]interface{}{
+		"result": "success",
+		"arguments": legacyArguments(result, legacyNames(reflect.TypeOf(response))),
+	})
+	if err != nil {
+		return &DecodeError{method, err}
+	}
+
+	return LegacyTransport{}.Decode(method, legacy, response)
+}
+
+// Message, with the daemon's error string if it provided one.
+func (e *jsonrpcError) describe() string {
+	var data struct {
+		ErrorString string `json:"error_string"`
+	}
+
+	if len(e.Data) > 0 && json.Unmarshal(e.Data, &data) == nil && data.ErrorString != "" {
+		return fmt.Sprintf("%s: %s", e.Message, data.ErrorString)
+	}
+	return e.Message
+}
+
+/* Naming */
+
+// Converts legacy names, like 'torrent-get', 'peer-limit' or 'isUTP', into
+// JSON-RPC ones: 'torrent_get', 'peer_limit', 'is_utp'.
+func SnakeCase(name string) string {
+	runes := []rune(name)
+	output := make([]rune, 0, len(runes) + 4)
+
+	for index, symbol := range runes {
+		if symbol == '-' {
+			output = append(output, '_')
+			continue
+		}
+
+		if unicode.IsUpper(symbol) && index > 0 {
+			previous := runes[index - 1]
+			// Either a start of a word, or the last capital of an acronym
+			// followed by a word, like 'P' in 'UTPPeer'.
+			wordStart := unicode.IsLower(previous) || unicode.IsDigit(previous)
+			acronymEnd := unicode.IsUpper(previous) &&
+				index + 1 < len(runes) && unicode.IsLower(runes[index + 1])
+
+			if wordStart || acronymEnd {
+				output = append(output, '_')
+			}
+		}
+
+		output = append(output, unicode.ToLower(symbol))
+	}
+
+	return string(output)
+}
+
+// Renames keys at every level, and argument values that are names
+// themselves.
+func snakeCaseArguments(value interface{}) interface{} {
+	switch typed := value.(type) {
+	case []interface{}:
+		output := make([]interface{}, len(typed))
+		for index, item := range typed {
+			output[index] = snakeCaseArguments(item)
+		}
+		return output
+	case map[string]interface{}:
+		return snakeCaseObject(typed)
+	}
+	return value
+}
+
+func snakeCaseObject(arguments map[string]interface{}) map[string]interface{} {
+	output := make(map[string]interface{}, len(arguments))
+	for key, item := range arguments {
+		switch key {
+		case "fields":
+			if list, ok := item.([]interface{}); ok {
+				fields := make([]interface{}, len(list))
+				for index, field := range list {
+					if name, ok := field.(string); ok {
+						fields[index] = SnakeCase(name)
+					} else {
+						fields[index] = field
+					}
+				}
+				item = fields
+			}
+		case "ids":
+			// 'recently-active'.
+			if name, ok := item.(string); ok {
+				item = SnakeCase(name)
+			}
+		default:
+			item = snakeCaseArguments(item)
+		}
+		output[SnakeCase(key)] = item
+	}
+	return output
+}
+
+// Renames keys back to legacy names known to the response type, at every
+// level. Keys are looked up by their snake case form, so ones a daemon still
+// sends in legacy spelling, like 'peer-limit' or 'fileStats', match too.
+// Unknown keys are kept as is. Header row of a table-formatted torrent list
+// is renamed too.
+func legacyArguments(value interface{}, names map[string]string) interface{} {
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		output := make(map[string]interface{}, len(typed))
+		for key, item := range typed {
+			key = legacyName(key, names)
+
+			if key == "torrents" {
+				item = legacyTableHeader(item, names)
+			}
+			output[key] = legacyArguments(item, names)
+		}
+		return output
+	case []interface{}:
+		output := make([]interface{}, len(typed))
+		for index, item := range typed {
+			output[index] = legacyArguments(item, names)
+		}
+		return output
+	}
+	return value
+}
+
+func legacyTableHeader(value interface{}, names map[string]string) interface{} {
+	rows, ok := value.([]interface{})
+	if !ok || len(rows) == 0 {
+		return value
+	}
+
+	header, ok := rows[0].([]interface{})
+	if !ok {
+		return value
+	}
+
+	renamed := make([]interface{}, len(header))
+	for index, cell := range header {
+		if name, ok := cell.(string); ok {
+			cell = legacyName(name, names)
+		}
+		renamed[index] = cell
+	}
+
+	output := append([]interface{}{ renamed }, rows[1:]...)
+	return output
+}
+
+func legacyName(name string, names map[string]string) string {
+	if legacy, ok := names[SnakeCase(name)]; ok {
+		return legacy
+	}
+	return name
+}
+
+var legacyNamesLock sync.Mutex
+var legacyNamesCache = map[reflect.Type]map[string]string{}
+
+// Snake case to legacy name mapping for all JSON field names reachable from
+// the type. Computed once per type.
+func legacyNames(root reflect.Type) map[string]string {
+	legacyNamesLock.Lock()
+	defer legacyNamesLock.Unlock()
+
+	if names, ok := legacyNamesCache[root]; ok {
+		return names
+	}
+
+	names := map[string]string{}
+	visited := map[reflect.Type]bool{}
+
+	var walk func(reflect.Type)
+	walk = func(current reflect.Type) {
+		switch current.Kind() {
+		case reflect.Ptr, reflect.Slice, reflect.Array, reflect.Map:
+			walk(current.Elem())
+		case reflect.Struct:
+			if visited[current] {
+				return
+			}
+			visited[current] = true
+
+			for index := 0; index < current.NumField(); index++ {
+				field := current.Field(index)
+				name := strings.Split(field.Tag.Get("json"), ",")[0]
+				if name != "" && name != "-" {
+					names[SnakeCase(name)] = name
+				}
+				walk(field.Type)
+			}
+		}
+	}
+
+	if root != nil {
+		walk(root)
+	}
+
+	legacyNamesCache[root] = names
+	return names
+}
+
+/* Helpers */
+
+// Round-trips a value through JSON into maps and slices.
+func toGeneric(value interface{}) (interface{}, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	return decodeGeneric(data)
+}
+
+// Keeps numbers as json.Number, so large integers survive re-encoding.
+func decodeGeneric(data []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var output interface{}
+	if err := decoder.Decode(&output); err != nil {
+		return nil, err
+	}
+	return output, nil
+}
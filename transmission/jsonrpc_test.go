@@ -0,0 +1,80 @@
+package transmission
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestSnakeCase(t *testing.T) {
+	names := map[string]string{
+		"torrent-get": "torrent_get",
+		"peer-limit": "peer_limit",
+		"file-count": "file_count",
+		"primary-mime-type": "primary_mime_type",
+		"isUTP": "is_utp",
+		"fileStats": "file_stats",
+		"peersFrom": "peers_from",
+		"fromDht": "from_dht",
+		"speed-limit-down-enabled": "speed_limit_down_enabled",
+		"already_snake": "already_snake",
+	}
+
+	for legacy, expected := range names {
+		if name := SnakeCase(legacy); name != expected {
+			t.Errorf("SnakeCase(%q) = %q, expected %q", legacy, name, expected)
+		}
+	}
+}
+
+// Nested objects in parameters are renamed too, values aren't.
+func TestEncodeNested(t *testing.T) {
+	request := TRequest{
+		Method: "session-set",
+		Arguments: map[string]interface{}{
+			"peer-limit-global": 10,
+			"nestedObject": map[string]interface{}{ "innerKey": "valueName" },
+			"fields": []string{ "peer-limit", "fileStats" },
+		},
+	}
+
+	data, err := (&JSONRPCTransport{}).Encode(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]interface{}{
+		"peer_limit_global": float64(10),
+		"nested_object": map[string]interface{}{ "inner_key": "valueName" },
+		"fields": []interface{}{ "peer_limit", "file_stats" },
+	}
+	if decoded["method"] != "session_set" || !reflect.DeepEqual(decoded["params"], expected) {
+		t.Errorf("Unexpected request: %s", data)
+	}
+}
+
+// Daemons mid-migration may still send some keys in legacy spelling.
+func TestDecodeMixedSpelling(t *testing.T) {
+	body := []byte(`{"jsonrpc": "2.0", "id": 1, "result": {"torrents": [
+		{"id": 1, "peer-limit": 5, "file_count": 2, "fileStats": [{"bytes_completed": 7, "wanted": true}],
+		 "peers_from": {"fromDht": 3}}
+	]}}`)
+
+	var response TorrentGetResponse
+	if err := (&JSONRPCTransport{}).Decode("torrent-get", body, &response); err != nil {
+		t.Fatal(err)
+	}
+
+	torrent := (*response.ArgumentsValue.Torrents)[0]
+	if torrent.PeerLimit != 5 || torrent.FileCount != 2 || torrent.PeersFrom.FromDht != 3 {
+		t.Errorf("Unexpected torrent: %+v", torrent)
+	}
+	if len(torrent.FileStats) != 1 || torrent.FileStats[0].BytesCompleted != 7 || !torrent.FileStats[0].Wanted {
+		t.Errorf("Unexpected file stats: %+v", torrent.FileStats)
+	}
+}
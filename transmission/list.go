@@ -1,28 +1,20 @@
 package transmission
 
-import "net/http"
-
-func ListRequest(conn Connection, token string) (*http.Request, error) {
-	return TRequest{
-		conn,
-		"torrent-get",
-		token,
-		map[string]interface{} {
-			"fields": []string{
-				"error",
-				"errorString",
-				"eta",
-				"id",
-				"leftUntilDone",
-				"name",
-				"rateDownload",
-				"rateUpload",
-				"sizeWhenDone",
-				"status",
-				"downloadDir",
-				"uploadRatio",
-				"addedDate"}}}.ToRequest()
-}
+// Fields needed for the list screen.
+var LIST_FIELDS = []string{
+	"error",
+	"errorString",
+	"eta",
+	"id",
+	"leftUntilDone",
+	"name",
+	"rateDownload",
+	"rateUpload",
+	"sizeWhenDone",
+	"status",
+	"downloadDir",
+	"uploadRatio",
+	"addedDate"}
 
 type TorrentListItem struct {
 	TorrentId int					`json:"id"`
@@ -38,24 +30,18 @@ type TorrentListItem struct {
 	AddedDate int					`json:"addedDate"`
 }
 
-type TorrentListResponseArguments struct {
-	Torrents []TorrentListItem `json:"torrents"`
-}
-
-type TorrentListResponse struct {
-	ResultValue string													`json:"result"`
-	TagValue string															`json:"tag"`
-	ArgumentsValue TorrentListResponseArguments `json:"arguments"`
-}
-
-func (response TorrentListResponse) Result() string {
-	return response.ResultValue
-}
-
-func (response TorrentListResponse) Tag() string {
-	return response.TagValue
-}
-
-func (response TorrentListResponse) Arguments() interface{} {
-	return response.ArgumentsValue
+func NewTorrentListItem(torrent Torrent) TorrentListItem {
+	return TorrentListItem{
+		torrent.Id,
+		torrent.Name,
+		float32(torrent.RateUpload),
+		float32(torrent.RateDownload),
+		float32(torrent.UploadRatio),
+		int32(torrent.Eta),
+		torrent.SizeWhenDone,
+		torrent.LeftUntilDone,
+		int8(torrent.Status),
+		torrent.DownloadDir,
+		int(torrent.AddedDate),
+	}
 }
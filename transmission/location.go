@@ -1,16 +1,12 @@
 package transmission
 
-import "net/http"
-
 func SetLocationRequest(ids []int, value string) RequestBuilder {
-	return func(conn Connection, token string) (*http.Request, error) {
+	return func() TRequest {
 		return TRequest{
-			conn,
 			"torrent-set-location",
-			token,
 			map[string]interface{}{
 				"ids": ids,
 				"location": value,
-				"move": true}}.ToRequest()
+				"move": true}}
 	}
 }
@@ -1,9 +0,0 @@
-package transmission
-
-func RefreshRequest(conn Connection) TRequest {
-	return TRequest{
-		conn,
-		"",
-		"",
-		nil}
-}
@@ -1,18 +1,14 @@
 package transmission
 
-import "net/http"
-
-func GetSessionSettingsRequest(conn Connection, token string) (*http.Request, error) {
+func GetSessionSettingsRequest() TRequest {
 	return TRequest{
-		conn,
 		"session-get",
-		token,
 		map[string]interface{}{
 			"fields": []string{
 				"speed-limit-up",
 				"speed-limit-up-enabled",
 				"speed-limit-down",
-				"speed-limit-down-enabled"}}}.ToRequest()
+				"speed-limit-down-enabled"}}}
 }
 
 type SessionSettings struct {
@@ -0,0 +1,128 @@
+package transmission
+
+import (
+	"errors"
+	"time"
+)
+
+type ConnectionState int
+
+const (
+	CONNECTION_CONNECTING ConnectionState = iota /* No requests completed yet */
+	CONNECTION_CONNECTED                         /* Last request reached the daemon */
+	CONNECTION_RECONNECTING                      /* Retrying after a failure */
+	CONNECTION_FAILED                            /* Last attempt failed, waiting for NextRetry */
+)
+
+const (
+	BACKOFF_MIN = 1 * time.Second
+	BACKOFF_MAX = 60 * time.Second
+)
+
+type ConnectionStatus struct {
+	State ConnectionState
+	// Error that caused the last failure. Nil while connected.
+	LastError error
+	// Requests made before this moment fail immediately with OfflineError.
+	NextRetry time.Time
+	// Number of consecutive failed attempts.
+	Attempts int
+}
+
+func (status ConnectionStatus) IsOnline() bool {
+	return status.State == CONNECTION_CONNECTED
+}
+
+// Delay before the next attempt, doubled after every consecutive failure.
+func backoff(attempts int) time.Duration {
+	delay := BACKOFF_MIN
+	for i := 1; i < attempts && delay < BACKOFF_MAX; i++ {
+		delay *= 2
+	}
+
+	if delay > BACKOFF_MAX {
+		return BACKOFF_MAX
+	}
+	return delay
+}
+
+// Whether the error means the daemon can't be used right now,
+// as opposed to a single failed RPC call.
+func isConnectionFailure(err error) bool {
+	for _, target := range []error{ ErrUnreachable, ErrUnauthorized, ErrSessionConflict } {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+
+	var httpError *HTTPError
+	return errors.As(err, &httpError) && httpError.StatusCode >= 500
+}
+
+/* Client state transitions */
+
+func (client *Client) Status() ConnectionStatus {
+	client.lock.RLock()
+	defer client.lock.RUnlock()
+	return client.status
+}
+
+func (client *Client) IsConnected() bool {
+	return client.Status().IsOnline()
+}
+
+// Drops the backoff delay, so the next request goes to the daemon right away.
+func (client *Client) Reconnect() {
+	client.lock.Lock()
+	defer client.lock.Unlock()
+
+	if client.status.State == CONNECTION_FAILED {
+		client.status.NextRetry = time.Now()
+	}
+}
+
+// Called before each request. Fails fast while waiting out the backoff delay.
+func (client *Client) beginRequest() error {
+	client.lock.Lock()
+	defer client.lock.Unlock()
+
+	if client.status.State != CONNECTION_FAILED {
+		return nil
+	}
+
+	if time.Now().Before(client.status.NextRetry) {
+		return &OfflineError{client.status.LastError, client.status.NextRetry}
+	}
+
+	client.status.State = CONNECTION_RECONNECTING
+	return nil
+}
+
+func (client *Client) markConnected() {
+	client.lock.Lock()
+	defer client.lock.Unlock()
+
+	if client.status.State != CONNECTION_CONNECTED {
+		client.sessions += 1
+	}
+	client.status = ConnectionStatus{ State: CONNECTION_CONNECTED }
+}
+
+func (client *Client) session() int {
+	client.lock.RLock()
+	defer client.lock.RUnlock()
+	return client.sessions
+}
+
+func (client *Client) markFailed(err error) {
+	client.lock.Lock()
+	defer client.lock.Unlock()
+
+	attempts := client.status.Attempts + 1
+	client.status = ConnectionStatus{
+		State: CONNECTION_FAILED,
+		LastError: err,
+		NextRetry: time.Now().Add(backoff(attempts)),
+		Attempts: attempts,
+	}
+}
@@ -0,0 +1,132 @@
+package transmission
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+/* Table format */
+
+// Since RPC version 16 'torrent-get' can return torrents as a table: first row
+// holds field names, other rows hold values in the same order. Keys aren't
+// repeated for every torrent, so long lists are a lot smaller.
+
+// Asks for the table format in a 'torrent-get' request.
+func WithTableFormat(builder RequestBuilder) RequestBuilder {
+	return func() TRequest {
+		request := builder()
+		if arguments, ok := request.Arguments.(map[string]interface{}); ok {
+			arguments["format"] = "table"
+		}
+		return request
+	}
+}
+
+// Accepts both formats, so the caller doesn't need to remember which one
+// was requested.
+func (arguments *TorrentGetResponseArguments) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Torrents *json.RawMessage `json:"torrents"`
+		Removed []int							`json:"removed"`
+	}
+
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	arguments.Torrents, arguments.Removed = nil, raw.Removed
+	if raw.Torrents == nil {
+		return nil
+	}
+
+	var torrents []Torrent
+	var err error
+	if isTable(*raw.Torrents) {
+		torrents, err = decodeTable(*raw.Torrents)
+	} else {
+		err = json.Unmarshal(*raw.Torrents, &torrents)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	arguments.Torrents = &torrents
+	return nil
+}
+
+// Table is an array of arrays, object list is an array of objects.
+func isTable(data []byte) bool {
+	data = bytes.TrimLeft(data, " \t\r\n")
+	if len(data) == 0 || data[0] != '[' {
+		return false
+	}
+
+	data = bytes.TrimLeft(data[1:], " \t\r\n")
+	return len(data) > 0 && data[0] == '['
+}
+
+func decodeTable(data []byte) ([]Torrent, error) {
+	var rows [][]json.RawMessage
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return nil, err
+	}
+
+	if len(rows) == 0 {
+		return []Torrent{}, nil
+	}
+
+	// Map columns to struct fields. Unknown columns are skipped.
+	fieldIndexes := torrentFieldIndexes()
+	columns := make([]int, len(rows[0]))
+	for column, cell := range rows[0] {
+		var name string
+		if err := json.Unmarshal(cell, &name); err != nil {
+			return nil, err
+		}
+
+		if index, ok := fieldIndexes[name]; ok {
+			columns[column] = index
+		} else {
+			columns[column] = -1
+		}
+	}
+
+	torrents := make([]Torrent, len(rows) - 1)
+	for row, cells := range rows[1:] {
+		value := reflect.ValueOf(&torrents[row]).Elem()
+		for column, cell := range cells {
+			if column >= len(columns) || columns[column] < 0 {
+				continue
+			}
+
+			field := value.Field(columns[column]).Addr().Interface()
+			if err := json.Unmarshal(cell, field); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return torrents, nil
+}
+
+var torrentFieldsOnce sync.Once
+var torrentFieldsByName map[string]int
+
+// Torrent's struct field indexes keyed by their JSON names.
+func torrentFieldIndexes() map[string]int {
+	torrentFieldsOnce.Do(func() {
+		torrentType := reflect.TypeOf(Torrent{})
+		torrentFieldsByName = make(map[string]int, torrentType.NumField())
+		for index := 0; index < torrentType.NumField(); index++ {
+			tag := torrentType.Field(index).Tag.Get("json")
+			if name := strings.Split(tag, ",")[0]; name != "" && name != "-" {
+				torrentFieldsByName[name] = index
+			}
+		}
+	})
+	return torrentFieldsByName
+}
@@ -0,0 +1,120 @@
+package transmission
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+const BENCHMARK_TORRENTS = 5000
+
+// List screen fields of a made-up torrent.
+func syntheticTorrent(index int) map[string]interface{} {
+	return map[string]interface{}{
+		"error": 0,
+		"errorString": "",
+		"eta": index * 60,
+		"id": index + 1,
+		"leftUntilDone": int64(index) * 1024 * 1024,
+		"name": fmt.Sprintf("Synthetic torrent number %d with a long enough name", index),
+		"rateDownload": index * 100,
+		"rateUpload": index * 10,
+		"sizeWhenDone": int64(index + 1) * 4 * 1024 * 1024,
+		"status": index % 7,
+		"downloadDir": "/var/lib/transmission/downloads",
+		"uploadRatio": float64(index % 100) / 10,
+		"addedDate": 1600000000 + index,
+	}
+}
+
+func objectsResponse(count int) []byte {
+	torrents := make([]map[string]interface{}, count)
+	for index := range torrents {
+		torrents[index] = syntheticTorrent(index)
+	}
+	return mustMarshal(map[string]interface{}{
+		"result": "success",
+		"arguments": map[string]interface{}{ "torrents": torrents },
+	})
+}
+
+func tableResponse(count int) []byte {
+	rows := [][]interface{}{}
+
+	header := make([]interface{}, len(LIST_FIELDS))
+	for column, field := range LIST_FIELDS {
+		header[column] = field
+	}
+	rows = append(rows, header)
+
+	for index := 0; index < count; index++ {
+		torrent := syntheticTorrent(index)
+		row := make([]interface{}, len(LIST_FIELDS))
+		for column, field := range LIST_FIELDS {
+			row[column] = torrent[field]
+		}
+		rows = append(rows, row)
+	}
+
+	return mustMarshal(map[string]interface{}{
+		"result": "success",
+		"arguments": map[string]interface{}{ "torrents": rows },
+	})
+}
+
+func mustMarshal(value interface{}) []byte {
+	data, err := json.Marshal(value)
+	if err != nil {
+		panic(err)
+	}
+	return data
+}
+
+func benchmarkTorrentGet(b *testing.B, data []byte) {
+	b.ReportAllocs()
+	b.SetBytes(int64(len(data)))
+	b.ResetTimer()
+
+	for n := 0; n < b.N; n++ {
+		var response TorrentGetResponse
+		if err := json.Unmarshal(data, &response); err != nil {
+			b.Fatal(err)
+		}
+		if torrents := response.ArgumentsValue.Torrents; torrents == nil || len(*torrents) != BENCHMARK_TORRENTS {
+			b.Fatal("Wrong number of torrents decoded")
+		}
+	}
+
+	// Reported after the loop, ResetTimer drops custom metrics.
+	b.ReportMetric(float64(len(data)), "payload-bytes")
+}
+
+func BenchmarkTorrentGetObjects(b *testing.B) {
+	benchmarkTorrentGet(b, objectsResponse(BENCHMARK_TORRENTS))
+}
+
+func BenchmarkTorrentGetTable(b *testing.B) {
+	benchmarkTorrentGet(b, tableResponse(BENCHMARK_TORRENTS))
+}
+
+// Both formats have to decode into the same torrents.
+func TestTableMatchesObjects(t *testing.T) {
+	var objects, table TorrentGetResponse
+	if err := json.Unmarshal(objectsResponse(10), &objects); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(tableResponse(10), &table); err != nil {
+		t.Fatal(err)
+	}
+
+	left, right := *objects.ArgumentsValue.Torrents, *table.ArgumentsValue.Torrents
+	if len(left) != 10 || len(right) != 10 {
+		t.Fatalf("Decoded %d and %d torrents, expected 10", len(left), len(right))
+	}
+
+	for index := range left {
+		if fmt.Sprintf("%+v", left[index]) != fmt.Sprintf("%+v", right[index]) {
+			t.Errorf("Torrent %d differs:\n%+v\n%+v", index, left[index], right[index])
+		}
+	}
+}
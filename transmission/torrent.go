@@ -0,0 +1,329 @@
+package transmission
+
+import (
+	"encoding/json"
+)
+
+/* Fields */
+
+// All 'torrent-get' fields known to the client. Daemons silently skip the
+// ones they don't support.
+var TORRENT_FIELDS = []string{
+	"activityDate",
+	"addedDate",
+	"availability",
+	"bandwidthPriority",
+	"comment",
+	"corruptEver",
+	"creator",
+	"dateCreated",
+	"desiredAvailable",
+	"doneDate",
+	"downloadDir",
+	"downloadedEver",
+	"downloadLimit",
+	"downloadLimited",
+	"editDate",
+	"error",
+	"errorString",
+	"eta",
+	"etaIdle",
+	"file-count",
+	"files",
+	"fileStats",
+	"group",
+	"hashString",
+	"haveUnchecked",
+	"haveValid",
+	"honorsSessionLimits",
+	"id",
+	"isFinished",
+	"isPrivate",
+	"isStalled",
+	"labels",
+	"leftUntilDone",
+	"magnetLink",
+	"manualAnnounceTime",
+	"maxConnectedPeers",
+	"metadataPercentComplete",
+	"name",
+	"peer-limit",
+	"peers",
+	"peersConnected",
+	"peersFrom",
+	"peersGettingFromUs",
+	"peersSendingToUs",
+	"percentComplete",
+	"percentDone",
+	"pieces",
+	"pieceCount",
+	"pieceSize",
+	"priorities",
+	"primary-mime-type",
+	"queuePosition",
+	"rateDownload",
+	"rateUpload",
+	"recheckProgress",
+	"secondsDownloading",
+	"secondsSeeding",
+	"seedIdleLimit",
+	"seedIdleMode",
+	"seedRatioLimit",
+	"seedRatioMode",
+	"sequentialDownload",
+	"sizeWhenDone",
+	"startDate",
+	"status",
+	"torrentFile",
+	"totalSize",
+	"trackerList",
+	"trackerStats",
+	"trackers",
+	"uploadLimit",
+	"uploadLimited",
+	"uploadRatio",
+	"uploadedEver",
+	"wanted",
+	"webseeds",
+	"webseedsSendingToUs",
+}
+
+/* Data */
+
+// Boolean that older daemons send as 0/1.
+type Flag bool
+
+func (flag *Flag) UnmarshalJSON(data []byte) error {
+	var number int
+	if err := json.Unmarshal(data, &number); err == nil {
+		*flag = number != 0
+		return nil
+	}
+
+	var value bool
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	*flag = Flag(value)
+	return nil
+}
+
+type File struct {
+	BytesCompleted int64 `json:"bytesCompleted"`
+	Length int64				 `json:"length"`
+	Name string					 `json:"name"`
+	BeginPiece int			 `json:"begin_piece"`
+	EndPiece int				 `json:"end_piece"`
+}
+
+type FileStat struct {
+	BytesCompleted int64 `json:"bytesCompleted"`
+	Wanted bool					 `json:"wanted"`
+	Priority int				 `json:"priority"`
+}
+
+type Peer struct {
+	Address string					`json:"address"`
+	Port int								`json:"port"`
+	ClientName string				`json:"clientName"`
+	FlagStr string					`json:"flagStr"`
+	Progress float64				`json:"progress"`
+	RateToClient int64			`json:"rateToClient"`
+	RateToPeer int64				`json:"rateToPeer"`
+	ClientIsChoked bool			`json:"clientIsChoked"`
+	ClientIsInterested bool `json:"clientIsInterested"`
+	PeerIsChoked bool				`json:"peerIsChoked"`
+	PeerIsInterested bool		`json:"peerIsInterested"`
+	IsDownloadingFrom bool	`json:"isDownloadingFrom"`
+	IsUploadingTo bool			`json:"isUploadingTo"`
+	IsEncrypted bool				`json:"isEncrypted"`
+	IsIncoming bool					`json:"isIncoming"`
+	IsUTP bool							`json:"isUTP"`
+}
+
+type PeersFrom struct {
+	FromCache int		 `json:"fromCache"`
+	FromDht int			 `json:"fromDht"`
+	FromIncoming int `json:"fromIncoming"`
+	FromLpd int			 `json:"fromLpd"`
+	FromLtep int		 `json:"fromLtep"`
+	FromPex int			 `json:"fromPex"`
+	FromTracker int	 `json:"fromTracker"`
+}
+
+type Tracker struct {
+	Id int					`json:"id"`
+	Announce string `json:"announce"`
+	Scrape string		`json:"scrape"`
+	Sitename string `json:"sitename"`
+	Tier int				`json:"tier"`
+}
+
+type TrackerStat struct {
+	TrackerId int								`json:"id"`
+	Announce string							`json:"announce"`
+	Scrape string								`json:"scrape"`
+	Host string									`json:"host"`
+	Sitename string							`json:"sitename"`
+	Tier int										`json:"tier"`
+	IsBackup bool								`json:"isBackup"`
+	AnnounceState int						`json:"announceState"`
+	ScrapeState int							`json:"scrapeState"`
+	HasAnnounced bool						`json:"hasAnnounced"`
+	HasScraped bool							`json:"hasScraped"`
+	LastAnnounceTime int64			`json:"lastAnnounceTime"`
+	LastAnnounceStartTime int64 `json:"lastAnnounceStartTime"`
+	LastAnnounceSucceeded bool	`json:"lastAnnounceSucceeded"`
+	LastAnnounceTimedOut bool		`json:"lastAnnounceTimedOut"`
+	LastAnnounceResult string		`json:"lastAnnounceResult"`
+	LastAnnouncePeerCount int		`json:"lastAnnouncePeerCount"`
+	LastScrapeTime int64				`json:"lastScrapeTime"`
+	LastScrapeStartTime int64		`json:"lastScrapeStartTime"`
+	LastScrapeSucceeded bool		`json:"lastScrapeSucceeded"`
+	LastScrapeTimedOut Flag			`json:"lastScrapeTimedOut"`
+	LastScrapeResult string			`json:"lastScrapeResult"`
+	NextAnnounceTime int64			`json:"nextAnnounceTime"`
+	NextScrapeTime int64				`json:"nextScrapeTime"`
+	SeederCount int							`json:"seederCount"`
+	LeecherCount int						`json:"leecherCount"`
+	DownloadCount int						`json:"downloadCount"`
+}
+
+// Torrent as described by the 'torrent-get' spec. Only the requested fields
+// are filled in, the rest keep their zero values.
+type Torrent struct {
+	ActivityDate int64							`json:"activityDate"`
+	AddedDate int64									`json:"addedDate"`
+	Availability []int							`json:"availability"`
+	BandwidthPriority int						`json:"bandwidthPriority"`
+	Comment string									`json:"comment"`
+	CorruptEver int64								`json:"corruptEver"`
+	Creator string									`json:"creator"`
+	DateCreated int64								`json:"dateCreated"`
+	DesiredAvailable int64					`json:"desiredAvailable"`
+	DoneDate int64									`json:"doneDate"`
+	DownloadDir string							`json:"downloadDir"`
+	DownloadedEver int64						`json:"downloadedEver"`
+	DownloadLimit int								`json:"downloadLimit"`
+	DownloadLimited bool						`json:"downloadLimited"`
+	EditDate int64									`json:"editDate"`
+	Error int												`json:"error"`
+	ErrorString string							`json:"errorString"`
+	Eta int64												`json:"eta"`
+	EtaIdle int64										`json:"etaIdle"`
+	FileCount int										`json:"file-count"`
+	Files []File										`json:"files"`
+	FileStats []FileStat						`json:"fileStats"`
+	Group string										`json:"group"`
+	HashString string								`json:"hashString"`
+	HaveUnchecked int64							`json:"haveUnchecked"`
+	HaveValid int64									`json:"haveValid"`
+	HonorsSessionLimits bool				`json:"honorsSessionLimits"`
+	Id int													`json:"id"`
+	IsFinished bool									`json:"isFinished"`
+	IsPrivate bool									`json:"isPrivate"`
+	IsStalled bool									`json:"isStalled"`
+	Labels []string									`json:"labels"`
+	LeftUntilDone int64							`json:"leftUntilDone"`
+	MagnetLink string								`json:"magnetLink"`
+	ManualAnnounceTime int64				`json:"manualAnnounceTime"`
+	MaxConnectedPeers int						`json:"maxConnectedPeers"`
+	MetadataPercentComplete float64 `json:"metadataPercentComplete"`
+	Name string											`json:"name"`
+	PeerLimit int										`json:"peer-limit"`
+	Peers []Peer										`json:"peers"`
+	PeersConnected int							`json:"peersConnected"`
+	PeersFrom PeersFrom							`json:"peersFrom"`
+	PeersGettingFromUs int					`json:"peersGettingFromUs"`
+	PeersSendingToUs int						`json:"peersSendingToUs"`
+	PercentComplete float64					`json:"percentComplete"`
+	PercentDone float64							`json:"percentDone"`
+	// Base64-encoded bitfield.
+	Pieces string										`json:"pieces"`
+	PieceCount int									`json:"pieceCount"`
+	PieceSize int64									`json:"pieceSize"`
+	Priorities []int								`json:"priorities"`
+	PrimaryMimeType string					`json:"primary-mime-type"`
+	QueuePosition int								`json:"queuePosition"`
+	// Bytes per second.
+	RateDownload int64							`json:"rateDownload"`
+	RateUpload int64								`json:"rateUpload"`
+	RecheckProgress float64					`json:"recheckProgress"`
+	SecondsDownloading int64				`json:"secondsDownloading"`
+	SecondsSeeding int64						`json:"secondsSeeding"`
+	SeedIdleLimit int								`json:"seedIdleLimit"`
+	SeedIdleMode int								`json:"seedIdleMode"`
+	SeedRatioLimit float64					`json:"seedRatioLimit"`
+	SeedRatioMode int								`json:"seedRatioMode"`
+	SequentialDownload bool					`json:"sequentialDownload"`
+	SizeWhenDone int64							`json:"sizeWhenDone"`
+	StartDate int64									`json:"startDate"`
+	Status int											`json:"status"`
+	TorrentFile string							`json:"torrentFile"`
+	TotalSize int64									`json:"totalSize"`
+	// Announce URLs, one per line, tiers separated by blank lines.
+	TrackerList string							`json:"trackerList"`
+	TrackerStats []TrackerStat			`json:"trackerStats"`
+	Trackers []Tracker							`json:"trackers"`
+	UploadLimit int									`json:"uploadLimit"`
+	UploadLimited bool							`json:"uploadLimited"`
+	UploadRatio float64							`json:"uploadRatio"`
+	UploadedEver int64							`json:"uploadedEver"`
+	Wanted []Flag										`json:"wanted"`
+	Webseeds []string								`json:"webseeds"`
+	WebseedsSendingToUs int					`json:"webseedsSendingToUs"`
+}
+
+/* Request */
+
+// Nil ids means all torrents.
+func TorrentGetRequest(ids []int, fields []string) RequestBuilder {
+	return func() TRequest {
+		arguments := map[string]interface{}{
+			"fields": fields,
+		}
+		if ids != nil {
+			arguments["ids"] = ids
+		}
+		return TRequest{ "torrent-get", arguments }
+	}
+}
+
+// Torrents changed within the last minute. Response also lists ids of
+// torrents removed during that time.
+func RecentlyActiveRequest(fields []string) RequestBuilder {
+	return func() TRequest {
+		return TRequest{
+			"torrent-get",
+			map[string]interface{}{
+				"ids": "recently-active",
+				"fields": fields,
+			},
+		}
+	}
+}
+
+/* Response */
+
+type TorrentGetResponseArguments struct {
+	Torrents *[]Torrent `json:"torrents"`
+	Removed []int				`json:"removed"`
+}
+
+type TorrentGetResponse struct {
+	ResultValue string												 `json:"result"`
+	TagValue string														 `json:"tag"`
+	ArgumentsValue TorrentGetResponseArguments `json:"arguments"`
+}
+
+func (response TorrentGetResponse) Result() string {
+	return response.ResultValue
+}
+
+func (response TorrentGetResponse) Tag() string {
+	return response.TagValue
+}
+
+func (response TorrentGetResponse) Arguments() interface{} {
+	return response.ArgumentsValue
+}
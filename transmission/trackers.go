@@ -0,0 +1,137 @@
+package transmission
+
+import (
+	"sort"
+	"strings"
+)
+
+/* Requests */
+
+// Deprecated since RPC version 17 in favor of 'trackerList', but the only way
+// to edit trackers on older daemons.
+
+func TrackerAddRequest(id int, urls []string) RequestBuilder {
+	return func() TRequest {
+		return TRequest{
+			"torrent-set",
+			map[string]interface{}{
+				"ids": []int{ id },
+				"trackerAdd": urls}}
+	}
+}
+
+func TrackerRemoveRequest(id int, trackers []int) RequestBuilder {
+	return func() TRequest {
+		return TRequest{
+			"torrent-set",
+			map[string]interface{}{
+				"ids": []int{ id },
+				"trackerRemove": trackers}}
+	}
+}
+
+// New announce URLs by tracker id.
+func TrackerReplaceRequest(id int, urls map[int]string) RequestBuilder {
+	trackers := make([]int, 0, len(urls))
+	for tracker := range urls {
+		trackers = append(trackers, tracker)
+	}
+	sort.Ints(trackers)
+
+	// Flat list of id and URL pairs.
+	pairs := []interface{}{}
+	for _, tracker := range trackers {
+		pairs = append(pairs, tracker, urls[tracker])
+	}
+
+	return func() TRequest {
+		return TRequest{
+			"torrent-set",
+			map[string]interface{}{
+				"ids": []int{ id },
+				"trackerReplace": pairs}}
+	}
+}
+
+// Replaces all trackers of the torrent at once.
+func TrackerListRequest(id int, tiers [][]string) RequestBuilder {
+	return func() TRequest {
+		return TRequest{
+			"torrent-set",
+			map[string]interface{}{
+				"ids": []int{ id },
+				"trackerList": FormatTrackerList(tiers)}}
+	}
+}
+
+/* Tiers */
+
+// Announce URLs grouped by tier, in tier order.
+func TrackerTiers(trackers []Tracker) [][]string {
+	sorted := append([]Tracker{}, trackers...)
+	sort.SliceStable(sorted, func(l, r int) bool {
+		return sorted[l].Tier < sorted[r].Tier
+	})
+
+	tiers := [][]string{}
+	for index, tracker := range sorted {
+		if index == 0 || tracker.Tier != sorted[index - 1].Tier {
+			tiers = append(tiers, []string{})
+		}
+		tiers[len(tiers) - 1] = append(tiers[len(tiers) - 1], tracker.Announce)
+	}
+	return tiers
+}
+
+// 'trackerList' format: one URL per line, tiers separated by blank lines.
+func FormatTrackerList(tiers [][]string) string {
+	lines := []string{}
+	for _, tier := range tiers {
+		if len(tier) > 0 {
+			lines = append(lines, strings.Join(tier, "\n"))
+		}
+	}
+	return strings.Join(lines, "\n\n")
+}
+
+// Parses 'trackerList' back into tiers.
+func ParseTrackerList(list string) [][]string {
+	tiers := [][]string{}
+	tier := []string{}
+	for _, line := range strings.Split(list, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			if len(tier) > 0 {
+				tiers, tier = append(tiers, tier), []string{}
+			}
+			continue
+		}
+		tier = append(tier, line)
+	}
+
+	if len(tier) > 0 {
+		tiers = append(tiers, tier)
+	}
+	return tiers
+}
+
+// Index of the tracker with the announce URL, or -1.
+func findTracker(trackers []Tracker, url string) int {
+	for index, tracker := range trackers {
+		if tracker.Announce == url {
+			return index
+		}
+	}
+	return -1
+}
+
+// Tier after the last one in use.
+func nextTier(trackers []Tracker) int {
+	tier := 0
+	for _, tracker := range trackers {
+		if tracker.Tier + 1 > tier {
+			tier = tracker.Tier + 1
+		}
+	}
+	return tier
+}
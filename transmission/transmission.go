@@ -1,7 +1,9 @@
 package transmission
 
 import (
+	"context"
 	"fmt"
+	"hash/fnv"
 )
 
 /* Data */
@@ -21,6 +23,13 @@ const (
 	TR_STATUS_SEED = 6					/* Seeding */
 )
 
+const (
+	TR_TRACKER_INACTIVE = 0			/* Not announcing */
+	TR_TRACKER_WAITING = 1			/* Waiting for the next announce */
+	TR_TRACKER_QUEUED = 2				/* Announce is queued */
+	TR_TRACKER_ACTIVE = 3				/* Announcing now */
+)
+
 const (
 	TR_PRIORITY_NORMAL = 0
 	TR_PRIORITY_HIGH = 1
@@ -37,109 +46,156 @@ func (torrent TorrentListItem) Id() int {
 	return torrent.TorrentId
 }
 
+func (tracker TrackerStat) Id() int {
+	return tracker.TrackerId
+}
+
+// Peers have no ids, but address and port are unique within a torrent.
+func (peer Peer) Id() int {
+	hash := fnv.New32a()
+	fmt.Fprintf(hash, "%s:%d", peer.Address, peer.Port)
+	return int(hash.Sum32())
+}
+
 /* Requests */
 
+// Every request has a context-aware variant with 'Context' suffix.
+// Plain variants use background context, bounded only by client's Timeout.
+
 func (client *Client) List() (*[]TorrentListItem, error) {
-	var response TorrentListResponse
-	err := client.performJson(ListRequest, &response)
+	return client.ListContext(context.Background())
+}
+
+// Returns cached list, updated with torrents changed since the last call.
+// See cache.go for details.
+func (client *Client) ListContext(ctx context.Context) (*[]TorrentListItem, error) {
+	return client.cache.update(ctx, client)
+}
+
+// Fetches given fields of given torrents. Nil ids means all torrents, no
+// fields means all fields from TORRENT_FIELDS.
+func (client *Client) TorrentGet(ids []int, fields ...string) ([]Torrent, error) {
+	return client.TorrentGetContext(context.Background(), ids, fields...)
+}
+
+func (client *Client) TorrentGetContext(ctx context.Context, ids []int, fields ...string) ([]Torrent, error) {
+	if len(fields) == 0 {
+		fields = TORRENT_FIELDS
+	}
+
+	var response TorrentGetResponse
+	builder := client.torrentGetBuilder(ctx, TorrentGetRequest(ids, fields))
+	err := client.performJson(ctx, builder, &response)
 
 	if err != nil {
 		return nil, err
 	}
 
-	args := response.Arguments().(TorrentListResponseArguments)
-	return &args.Torrents, err
+	args := response.Arguments().(TorrentGetResponseArguments)
+	if args.Torrents == nil {
+		return nil, &DecodeError{"torrent-get", fmt.Errorf("no torrents in response")}
+	}
+
+	return *args.Torrents, nil
+}
+
+// Fetches given fields of recently changed torrents, along with ids of
+// recently removed ones. No fields means all fields from TORRENT_FIELDS.
+func (client *Client) RecentlyActive(fields ...string) ([]Torrent, []int, error) {
+	return client.RecentlyActiveContext(context.Background(), fields...)
+}
+
+func (client *Client) RecentlyActiveContext(ctx context.Context, fields ...string) ([]Torrent, []int, error) {
+	if len(fields) == 0 {
+		fields = TORRENT_FIELDS
+	}
+
+	var response TorrentGetResponse
+	builder := client.torrentGetBuilder(ctx, RecentlyActiveRequest(fields))
+	err := client.performJson(ctx, builder, &response)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	args := response.Arguments().(TorrentGetResponseArguments)
+	if args.Torrents == nil {
+		return nil, nil, &DecodeError{"torrent-get", fmt.Errorf("no torrents in response")}
+	}
+
+	return *args.Torrents, args.Removed, nil
 }
 
 func (client *Client) Delete(ids []int, withData bool) error {
-	_, err := client.perform(DeleteRequest(ids, withData))
+	return client.DeleteContext(context.Background(), ids, withData)
+}
+
+func (client *Client) DeleteContext(ctx context.Context, ids []int, withData bool) error {
+	return client.performWithoutData(ctx, DeleteRequest(ids, withData))
+}
 
-	return err
+// Adds a torrent from a URL, a magnet link or a .torrent file. Local files are
+// uploaded as metainfo.
+func (client *Client) AddTorrent(url string, path string) (*AddResult, error) {
+	return client.AddTorrentContext(context.Background(), url, path)
 }
 
-func (client *Client) AddTorrent(url string, path string) (error) {
+func (client *Client) AddTorrentContext(ctx context.Context, url string, path string) (*AddResult, error) {
+	return client.AddTorrentWithOptionsContext(ctx, url, AddOptions{ DownloadDir: path })
+}
+
+func (client *Client) AddTorrentWithOptions(source string, options AddOptions) (*AddResult, error) {
+	return client.AddTorrentWithOptionsContext(context.Background(), source, options)
+}
+
+func (client *Client) AddTorrentWithOptionsContext(ctx context.Context, source string, options AddOptions) (*AddResult, error) {
+	if len(options.Labels) > 0 {
+		if err := client.require(ctx, FEATURE_LABELS); err != nil {
+			return nil, err
+		}
+	}
+
+	builder, err := addRequestFor(source, options)
+	if err != nil {
+		return nil, err
+	}
+
 	var response TorrentAddResponse
-	err := client.performJson(AddRequest(url, path, false), &response)
+	err = client.performJson(ctx, builder, &response)
 
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	args := response.Arguments().(TorrentAddResponseArguments)
-	if (args.Torrent == nil) {
-		return fmt.Errorf("Error: %s", response.Result())
-	} else {
-		return nil
+	switch {
+	case args.Torrent != nil:
+		return &AddResult{ *args.Torrent, false }, nil
+	case args.Duplicate != nil:
+		return &AddResult{ *args.Duplicate, true }, nil
+	default:
+		return nil, &DecodeError{"torrent-add", fmt.Errorf("no torrent in response")}
 	}
 }
 
-func (client *Client) TorrentDetails(id int) (*TorrentDetails, error) {
-	fields := []string{
-		"error",
-		"errorString",
-		"eta",
-		"id",
-		"leftUntilDone",
-		"name",
-		"rateDownload",
-		"rateUpload",
-		"sizeWhenDone",
-		"status",
-		"uploadRatio",
-		"downloadLimit",
-		"downloadLimited",
-		"uploadLimit",
-		"uploadLimited",
-		"files",
-		"downloadDir",
-		"fileStats"}
-
-	var response TorrentDetailsResponse
-	err := client.performJson(DetailsRequest(id, fields), &response)
+func (client *Client) TorrentDetails(id int, fields ...string) (*TorrentDetails, error) {
+	return client.TorrentDetailsContext(context.Background(), id, fields...)
+}
 
+// Fetches DETAILS_FIELDS and given extra fields, like the ones of a details
+// tab. Parts that weren't requested are left empty.
+func (client *Client) TorrentDetailsContext(ctx context.Context, id int, fields ...string) (*TorrentDetails, error) {
+	fields = append(append([]string{}, DETAILS_FIELDS...), fields...)
+	torrents, err := client.TorrentGetContext(ctx, []int{ id }, fields...)
 	if err != nil {
 		return nil, err
 	}
 
-	args := response.Arguments().(TorrentDetailsResponseArguments)
-	if args.Torrents == nil {
-		return nil, fmt.Errorf("%s", response.Result())
-	}
-
-	if len(*args.Torrents) == 0 {
+	if len(torrents) == 0 {
 		return nil, nil
 	}
 
-	internalTorrent := (*args.Torrents)[0]
-	files := make([]TorrentFile, len(*internalTorrent.Files))
-	for index, file := range (*internalTorrent.Files) {
-		files[index] = TorrentFile{
-			index,
-			file.BytesCompleted,
-			file.Length,
-			file.Name,
-			(*internalTorrent.FileStats)[index].Wanted,
-			(*internalTorrent.FileStats)[index].Priority}
-	}
-
-	torrent := TorrentDetails{
-		internalTorrent.Id,
-		internalTorrent.Name,
-		internalTorrent.UploadSpeed,
-		internalTorrent.DownloadSpeed,
-		internalTorrent.Ratio,
-		internalTorrent.Eta,
-		internalTorrent.SizeWhenDone,
-		internalTorrent.LeftUntilDone,
-		internalTorrent.Status,
-		internalTorrent.DownloadLimit,
-		internalTorrent.DownloadLimited,
-		internalTorrent.UploadLimit,
-		internalTorrent.UploadLimited,
-		internalTorrent.DownloadDir,
-		files,
-	}
-
+	torrent := NewTorrentDetails(torrents[0])
 	return &torrent, nil
 }
 
@@ -147,43 +203,182 @@ func (client *Client) SetPriority(
 	id int,
 	files []int,
 	priority int,
+) error {
+	return client.SetPriorityContext(context.Background(), id, files, priority)
+}
+
+func (client *Client) SetPriorityContext(
+	ctx context.Context,
+	id int,
+	files []int,
+	priority int,
 ) error {
 	return client.performWithoutData(
+		ctx,
 		SetPriorityRequest(id, files, priority),
 	)
 }
 
 func (client *Client) SetWanted(id int, files []int, wanted bool) error {
-	return client.performWithoutData(SetWantedRequest(id, files, wanted))
+	return client.SetWantedContext(context.Background(), id, files, wanted)
+}
+
+func (client *Client) SetWantedContext(ctx context.Context, id int, files []int, wanted bool) error {
+	return client.performWithoutData(ctx, SetWantedRequest(id, files, wanted))
 }
 
 func (client *Client) SetDownloadLimit(id int, limit int) error {
-	return client.performWithoutData(SetDownloadLimitRequest(id, limit))
+	return client.SetDownloadLimitContext(context.Background(), id, limit)
+}
+
+func (client *Client) SetDownloadLimitContext(ctx context.Context, id int, limit int) error {
+	return client.performWithoutData(ctx, SetDownloadLimitRequest(id, limit))
 }
 
 func (client *Client) SetUploadLimit(id int, limit int) error {
-	return client.performWithoutData(SetUploadLimitRequest(id, limit))
+	return client.SetUploadLimitContext(context.Background(), id, limit)
+}
+
+func (client *Client) SetUploadLimitContext(ctx context.Context, id int, limit int) error {
+	return client.performWithoutData(ctx, SetUploadLimitRequest(id, limit))
 }
 
 func (client *Client) SetLocation(ids []int, location string) error {
-	return client.performWithoutData(SetLocationRequest(ids, location))
+	return client.SetLocationContext(context.Background(), ids, location)
+}
+
+func (client *Client) SetLocationContext(ctx context.Context, ids []int, location string) error {
+	return client.performWithoutData(ctx, SetLocationRequest(ids, location))
 }
 
 func (client *Client) UpdateActive(ids []int, active bool) error {
-	return client.performWithoutData(UpdateActiveRequest(active, ids))
+	return client.UpdateActiveContext(context.Background(), ids, active)
+}
+
+func (client *Client) UpdateActiveContext(ctx context.Context, ids []int, active bool) error {
+	return client.performWithoutData(ctx, UpdateActiveRequest(active, ids))
 }
 
 func (client *Client) SetGlobalUploadLimit(limit int) error {
-	return client.performWithoutData(SetGlobalUploadLimitRequest(limit))
+	return client.SetGlobalUploadLimitContext(context.Background(), limit)
+}
+
+func (client *Client) SetGlobalUploadLimitContext(ctx context.Context, limit int) error {
+	return client.performWithoutData(ctx, SetGlobalUploadLimitRequest(limit))
 }
 
 func (client *Client) SetGlobalDownloadLimit(limit int) error {
-	return client.performWithoutData(SetGlobalDownloadLimitRequest(limit))
+	return client.SetGlobalDownloadLimitContext(context.Background(), limit)
+}
+
+func (client *Client) SetGlobalDownloadLimitContext(ctx context.Context, limit int) error {
+	return client.performWithoutData(ctx, SetGlobalDownloadLimitRequest(limit))
+}
+
+// Adds announce URLs to the torrent, each in a new tier. URLs the torrent
+// already has are skipped.
+func (client *Client) AddTrackers(id int, urls []string) error {
+	return client.AddTrackersContext(context.Background(), id, urls)
+}
+
+func (client *Client) AddTrackersContext(ctx context.Context, id int, urls []string) error {
+	return client.editTrackers(ctx, id, TrackerAddRequest(id, urls), func(trackers []Tracker) []Tracker {
+		output := append([]Tracker{}, trackers...)
+		for _, url := range urls {
+			if findTracker(output, url) < 0 {
+				output = append(output, Tracker{ Announce: url, Tier: nextTier(output) })
+			}
+		}
+		return output
+	})
+}
+
+// Removes trackers by their ids, as reported in 'trackers' or 'trackerStats'.
+func (client *Client) RemoveTrackers(id int, ids []int) error {
+	return client.RemoveTrackersContext(context.Background(), id, ids)
+}
+
+func (client *Client) RemoveTrackersContext(ctx context.Context, id int, ids []int) error {
+	return client.editTrackers(ctx, id, TrackerRemoveRequest(id, ids), func(trackers []Tracker) []Tracker {
+		output := []Tracker{}
+		for _, tracker := range trackers {
+			removed := false
+			for _, trackerId := range ids {
+				removed = removed || tracker.Id == trackerId
+			}
+			if !removed {
+				output = append(output, tracker)
+			}
+		}
+		return output
+	})
+}
+
+// Changes announce URL of the tracker, keeping its tier.
+func (client *Client) ReplaceTracker(id int, tracker int, url string) error {
+	return client.ReplaceTrackersContext(context.Background(), id, map[int]string{ tracker: url })
+}
+
+func (client *Client) ReplaceTrackerContext(ctx context.Context, id int, tracker int, url string) error {
+	return client.ReplaceTrackersContext(ctx, id, map[int]string{ tracker: url })
+}
+
+// Changes announce URLs of several trackers of the torrent in one request.
+// URLs are keyed by tracker id.
+func (client *Client) ReplaceTrackers(id int, urls map[int]string) error {
+	return client.ReplaceTrackersContext(context.Background(), id, urls)
+}
+
+func (client *Client) ReplaceTrackersContext(ctx context.Context, id int, urls map[int]string) error {
+	return client.editTrackers(ctx, id, TrackerReplaceRequest(id, urls), func(trackers []Tracker) []Tracker {
+		output := append([]Tracker{}, trackers...)
+		for index := range output {
+			if url, ok := urls[output[index].Id]; ok {
+				output[index].Announce = url
+			}
+		}
+		return output
+	})
+}
+
+// Daemons with 'trackerList' get the whole edited list, older ones get the
+// legacy request.
+func (client *Client) editTrackers(
+	ctx context.Context,
+	id int,
+	legacy RequestBuilder,
+	edit func([]Tracker) []Tracker,
+) error {
+	capabilities, err := client.CapabilitiesContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	if !capabilities.Supports(FEATURE_TRACKER_LIST) {
+		return client.performWithoutData(ctx, legacy)
+	}
+
+	torrents, err := client.TorrentGetContext(ctx, []int{ id }, "id", "trackers")
+	if err != nil {
+		return err
+	}
+
+	// Unknown ids are silently ignored, same as the daemon does.
+	if len(torrents) == 0 {
+		return nil
+	}
+
+	tiers := TrackerTiers(edit(torrents[0].Trackers))
+	return client.performWithoutData(ctx, TrackerListRequest(id, tiers))
 }
 
 func (client *Client) GetSessionSettings() (*SessionSettings, error) {
+	return client.GetSessionSettingsContext(context.Background())
+}
+
+func (client *Client) GetSessionSettingsContext(ctx context.Context) (*SessionSettings, error) {
 	var response SessionSettingsResponse
-	err := client.performJson(GetSessionSettingsRequest, &response)
+	err := client.performJson(ctx, GetSessionSettingsRequest, &response)
 
 	if err != nil {
 		return nil, err
@@ -194,5 +389,9 @@ func (client *Client) GetSessionSettings() (*SessionSettings, error) {
 }
 
 func (client *Client) Exit() error {
-	return client.performWithoutData(ExitRequest())
+	return client.ExitContext(context.Background())
+}
+
+func (client *Client) ExitContext(ctx context.Context) error {
+	return client.performWithoutData(ctx, ExitRequest())
 }
@@ -0,0 +1,48 @@
+package transmission
+
+import (
+	"encoding/json"
+)
+
+/* Transport */
+
+// Wire protocol used to talk to the daemon. Requests and responses are always
+// described in legacy terms, the transport converts them to and from its own
+// format.
+type Transport interface {
+	// Request body.
+	Encode(request TRequest) ([]byte, error)
+	// Fills the response from the body. Returns RPCError if daemon reported
+	// a failure, DecodeError if the body can't be read.
+	Decode(method string, body []byte, response TResponse) error
+}
+
+/* Legacy */
+
+// Original protocol: '{method, arguments, tag}' requests and
+// '{result, arguments, tag}' responses.
+type LegacyTransport struct {}
+
+func (transport LegacyTransport) Encode(request TRequest) ([]byte, error) {
+	var body = make(map[string]interface{})
+	if request.Method != "" {
+		body["method"] = request.Method
+	}
+	if request.Arguments != nil {
+		body["arguments"] = request.Arguments
+	}
+
+	return json.Marshal(body)
+}
+
+func (transport LegacyTransport) Decode(method string, body []byte, response TResponse) error {
+	if err := json.Unmarshal(body, &response); err != nil {
+		return &DecodeError{method, err}
+	}
+
+	if (response.Result() != "success") {
+		return &RPCError{method, response.Result()}
+	}
+
+	return nil
+}
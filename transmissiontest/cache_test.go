@@ -0,0 +1,163 @@
+package transmissiontest
+
+import (
+	"errors"
+	"testing"
+	"time"
+	"transmission"
+)
+
+// Client with its own clock, so the list cache's intervals can be controlled
+// separately from the daemon's time.
+func cacheClient(server *Server) (*transmission.Client, func(time.Duration)) {
+	now := time.Now()
+
+	client := server.Client()
+	client.Clock = func() time.Time { return now }
+	return client, func(duration time.Duration) { now = now.Add(duration) }
+}
+
+// Stopped torrents with metadata, which don't change on their own.
+func addIdle(daemon *Daemon, names ...string) []int {
+	files := []File{ File{ Name: "file", Length: 1024, Wanted: true } }
+
+	ids := make([]int, len(names))
+	for index, name := range names {
+		ids[index] = daemon.Add(Torrent{ Name: name, Status: transmission.TR_STATUS_STOPPED, Files: files })
+	}
+	return ids
+}
+
+func listIds(t *testing.T, client *transmission.Client) []int {
+	list, err := client.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ids := make([]int, len(*list))
+	for index, item := range *list {
+		ids[index] = item.TorrentId
+	}
+	return ids
+}
+
+func checkIds(t *testing.T, description string, ids []int, expected ...int) {
+	t.Helper()
+
+	if len(ids) != len(expected) {
+		t.Fatalf("%s: expected %v, got %v", description, expected, ids)
+	}
+	for index := range ids {
+		if ids[index] != expected[index] {
+			t.Fatalf("%s: expected %v, got %v", description, expected, ids)
+		}
+	}
+}
+
+// Removes a torrent long enough ago for 'recently-active' to forget it, so
+// only a full sync drops it from the list.
+func removeUnnoticed(daemon *Daemon, id int) {
+	daemon.Remove(id)
+	daemon.Advance(2 * RECENTLY_ACTIVE_SECONDS * time.Second)
+}
+
+func TestListMerge(t *testing.T) {
+	server := NewServer(nil)
+	defer server.Close()
+	daemon := server.Daemon
+	client, advance := cacheClient(server)
+
+	ids := addIdle(daemon, "removed", "started", "idle")
+	checkIds(t, "Initial list", listIds(t, client), ids...)
+
+	// Only changes within the window are merged in.
+	daemon.Advance(2 * RECENTLY_ACTIVE_SECONDS * time.Second)
+	daemon.Remove(ids[0])
+	if err := client.UpdateActive([]int{ ids[1] }, true); err != nil {
+		t.Fatal(err)
+	}
+	daemon.Add(Torrent{ Id: 10, Name: "added" })
+	advance(10 * time.Second)
+
+	calls := daemon.Calls("torrent-get")
+	list, err := client.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if daemon.Calls("torrent-get") != calls + 1 {
+		t.Fatal("Expected a single request")
+	}
+
+	items := *list
+	merged := make([]int, len(items))
+	for index, item := range items {
+		merged[index] = item.TorrentId
+	}
+	checkIds(t, "Merged list", merged, ids[1], ids[2], 10)
+	if items[0].Status != transmission.TR_STATUS_DOWNLOAD || items[1].Status != transmission.TR_STATUS_STOPPED {
+		t.Errorf("Unexpected statuses: %+v", items)
+	}
+}
+
+func TestListRecentlyActiveWindow(t *testing.T) {
+	server := NewServer(nil)
+	defer server.Close()
+	daemon := server.Daemon
+	client, advance := cacheClient(server)
+
+	ids := addIdle(daemon, "first", "second")
+	listIds(t, client)
+
+	// Polled within the window, so the removal is missed.
+	removeUnnoticed(daemon, ids[0])
+	advance(transmission.RECENTLY_ACTIVE_WINDOW - time.Second)
+	checkIds(t, "Poll within the window", listIds(t, client), ids...)
+
+	// Gap as long as the window means changes could've been missed.
+	advance(transmission.RECENTLY_ACTIVE_WINDOW)
+	checkIds(t, "Poll after a gap", listIds(t, client), ids[1])
+}
+
+func TestListResync(t *testing.T) {
+	server := NewServer(nil)
+	defer server.Close()
+	daemon := server.Daemon
+	client, advance := cacheClient(server)
+
+	ids := addIdle(daemon, "first", "second")
+	listIds(t, client)
+	removeUnnoticed(daemon, ids[0])
+
+	// Frequent polls don't help with the missed removal...
+	poll := transmission.RECENTLY_ACTIVE_WINDOW / 2
+	for elapsed := poll; elapsed < transmission.LIST_RESYNC_INTERVAL; elapsed += poll {
+		advance(poll)
+		checkIds(t, "Poll before resync", listIds(t, client), ids...)
+	}
+
+	// ...until the whole list is downloaded again.
+	advance(poll)
+	checkIds(t, "Poll after resync", listIds(t, client), ids[1])
+}
+
+func TestListReconnect(t *testing.T) {
+	server := NewServer(nil)
+	defer server.Close()
+	daemon := server.Daemon
+	client, advance := cacheClient(server)
+
+	ids := addIdle(daemon, "first", "second")
+	listIds(t, client)
+	removeUnnoticed(daemon, ids[0])
+
+	// Connection drops and comes back within the window.
+	daemon.SetCredentials("user", "secret")
+	if _, err := client.List(); !errors.Is(err, transmission.ErrUnauthorized) {
+		t.Fatalf("Expected ErrUnauthorized, got %v", err)
+	}
+	daemon.SetCredentials("", "")
+	client.Reconnect()
+	advance(time.Second)
+
+	checkIds(t, "List after reconnect", listIds(t, client), ids[1])
+}
@@ -0,0 +1,404 @@
+package transmissiontest
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+	"transmission"
+)
+
+const (
+	DEFAULT_DOWNLOAD_DIR = "/downloads"
+	// Torrents active within this window are returned for 'recently-active'.
+	RECENTLY_ACTIVE_SECONDS = 60
+)
+
+type removal struct {
+	id int
+	date int64
+}
+
+// Fake transmission-daemon. Keeps torrents in memory, speaks the legacy RPC
+// protocol including the session ID handshake and simulates transfers as
+// (simulated) time goes by.
+type Daemon struct {
+	lock sync.Mutex
+	torrents []*Torrent
+	removed []removal
+	nextId int
+	session map[string]interface{}
+	sessionId string
+	username string
+	password string
+	offset time.Duration
+	lastTick time.Time
+	closed bool
+	calls map[string]int
+	jsonrpcCalls int
+	// Set only for the demo daemon.
+	demo *demo
+}
+
+func NewDaemon() *Daemon {
+	daemon := &Daemon{
+		nextId: 1,
+		session: map[string]interface{}{
+			"version": "4.0.6 (38c164933e)",
+			"rpc-version": 17,
+			"rpc-version-minimum": 14,
+			"rpc-version-semver": "5.3.0",
+			"download-dir": DEFAULT_DOWNLOAD_DIR,
+			"speed-limit-up": 100,
+			"speed-limit-up-enabled": false,
+			"speed-limit-down": 100,
+			"speed-limit-down-enabled": false,
+			"peer-limit-global": 200,
+			"peer-limit-per-torrent": 50,
+		},
+		calls: make(map[string]int),
+	}
+	daemon.sessionId = newSessionId()
+	daemon.lastTick = daemon.now()
+	return daemon
+}
+
+/* Simulation control */
+
+// Current simulated time.
+func (daemon *Daemon) now() time.Time {
+	return time.Now().Add(daemon.offset)
+}
+
+// Moves simulated clock forward, running the transfers for the given duration.
+func (daemon *Daemon) Advance(duration time.Duration) {
+	daemon.lock.Lock()
+	defer daemon.lock.Unlock()
+
+	daemon.offset += duration
+	daemon.tick()
+}
+
+// Adds a torrent directly, bypassing the RPC. Missing id, dates and download
+// dir are filled in. Returns the torrent's id.
+func (daemon *Daemon) Add(torrent Torrent) int {
+	daemon.lock.Lock()
+	defer daemon.lock.Unlock()
+
+	return daemon.add(torrent)
+}
+
+// Removes a torrent as if it was removed by another client.
+func (daemon *Daemon) Remove(id int) {
+	daemon.lock.Lock()
+	defer daemon.lock.Unlock()
+
+	daemon.remove(id)
+}
+
+func (daemon *Daemon) Torrent(id int) (Torrent, bool) {
+	daemon.lock.Lock()
+	defer daemon.lock.Unlock()
+
+	if torrent := daemon.find(id); torrent != nil {
+		return torrent.copy(), true
+	}
+	return Torrent{}, false
+}
+
+func (daemon *Daemon) Torrents() []Torrent {
+	daemon.lock.Lock()
+	defer daemon.lock.Unlock()
+
+	output := make([]Torrent, len(daemon.torrents))
+	for index, torrent := range daemon.torrents {
+		output[index] = torrent.copy()
+	}
+	return output
+}
+
+// Changes a session setting, like 'rpc-version' or 'version'.
+func (daemon *Daemon) SetSession(key string, value interface{}) {
+	daemon.lock.Lock()
+	defer daemon.lock.Unlock()
+
+	daemon.session[key] = value
+}
+
+// Invalidates current session ID. Next request gets a 409 with the new one.
+func (daemon *Daemon) RotateSessionId() string {
+	daemon.lock.Lock()
+	defer daemon.lock.Unlock()
+
+	daemon.sessionId = newSessionId()
+	return daemon.sessionId
+}
+
+// Enables Basic authentication. Empty username disables it.
+func (daemon *Daemon) SetCredentials(username string, password string) {
+	daemon.lock.Lock()
+	defer daemon.lock.Unlock()
+
+	daemon.username, daemon.password = username, password
+}
+
+// Whether 'session-close' was received.
+func (daemon *Daemon) Closed() bool {
+	daemon.lock.Lock()
+	defer daemon.lock.Unlock()
+
+	return daemon.closed
+}
+
+// Number of successfully authenticated calls of the given RPC method.
+func (daemon *Daemon) Calls(method string) int {
+	daemon.lock.Lock()
+	defer daemon.lock.Unlock()
+
+	return daemon.calls[method]
+}
+
+// Number of calls made with the JSON-RPC protocol.
+func (daemon *Daemon) JSONRPCCalls() int {
+	daemon.lock.Lock()
+	defer daemon.lock.Unlock()
+
+	return daemon.jsonrpcCalls
+}
+
+/* HTTP */
+
+type rpcRequest struct {
+	Method string												`json:"method"`
+	Arguments map[string]interface{} `json:"arguments"`
+	Tag interface{}											`json:"tag"`
+}
+
+type rpcResponse struct {
+	Result string												`json:"result"`
+	Arguments map[string]interface{} `json:"arguments"`
+	Tag interface{}											`json:"tag,omitempty"`
+}
+
+func (daemon *Daemon) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	if request.Method != "POST" {
+		http.Error(writer, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	daemon.lock.Lock()
+	defer daemon.lock.Unlock()
+
+	if daemon.closed {
+		http.Error(writer, "Daemon is shutting down", http.StatusServiceUnavailable)
+		return
+	}
+
+	if daemon.username != "" {
+		username, password, ok := request.BasicAuth()
+		if !ok || username != daemon.username || password != daemon.password {
+			writer.Header().Set("WWW-Authenticate", "Basic realm=\"Transmission\"")
+			http.Error(writer, "Unauthorized User", http.StatusUnauthorized)
+			return
+		}
+	}
+
+	if request.Header.Get(transmission.SESSION_ID_HEADER) != daemon.sessionId {
+		writer.Header().Set(transmission.SESSION_ID_HEADER, daemon.sessionId)
+		http.Error(writer, "Invalid session ID", http.StatusConflict)
+		return
+	}
+
+	body, err := ioutil.ReadAll(request.Body)
+	if err != nil {
+		http.Error(writer, "Bad request", http.StatusBadRequest)
+		return
+	}
+
+	var response interface{}
+	if isJSONRPC(body) && toInt(daemon.session["rpc-version"]) >= transmission.FEATURE_JSON_RPC.Version() {
+		response = daemon.serveJSONRPC(body)
+	} else {
+		response = daemon.serveLegacy(body)
+	}
+
+	if response == nil {
+		http.Error(writer, "Bad request", http.StatusBadRequest)
+		return
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(writer).Encode(response)
+}
+
+// Nil if the request can't be parsed.
+func (daemon *Daemon) serveLegacy(body []byte) interface{} {
+	var rpc rpcRequest
+	if err := json.Unmarshal(body, &rpc); err != nil {
+		return nil
+	}
+
+	arguments, result := daemon.call(rpc.Method, rpc.Arguments)
+	return rpcResponse{ result, arguments, rpc.Tag }
+}
+
+func (daemon *Daemon) call(method string, arguments map[string]interface{}) (map[string]interface{}, string) {
+	if arguments == nil {
+		arguments = map[string]interface{}{}
+	}
+
+	daemon.calls[method] += 1
+	daemon.tick()
+
+	output, result := daemon.dispatch(method, arguments)
+	if output == nil {
+		output = map[string]interface{}{}
+	}
+	return output, result
+}
+
+/* Internals */
+
+func (daemon *Daemon) add(torrent Torrent) int {
+	if torrent.Id == 0 {
+		torrent.Id = daemon.nextId
+	}
+	if torrent.Id >= daemon.nextId {
+		daemon.nextId = torrent.Id + 1
+	}
+
+	now := daemon.now().Unix()
+	if torrent.AddedDate == 0 {
+		torrent.AddedDate = now
+	}
+	if torrent.ActivityDate == 0 {
+		torrent.ActivityDate = now
+	}
+	if torrent.DownloadDir == "" {
+		torrent.DownloadDir = daemon.session["download-dir"].(string)
+	}
+	if torrent.HashString == "" {
+		torrent.HashString = hashOf(torrent.Name)
+	}
+	if len(torrent.Files) > 0 && torrent.MetadataPercentComplete == 0 {
+		torrent.MetadataPercentComplete = 1
+	}
+
+	daemon.torrents = append(daemon.torrents, &torrent)
+	sort.Slice(daemon.torrents, func(l, r int) bool {
+		return daemon.torrents[l].Id < daemon.torrents[r].Id
+	})
+
+	return torrent.Id
+}
+
+func (daemon *Daemon) remove(id int) {
+	for index, torrent := range daemon.torrents {
+		if torrent.Id == id {
+			daemon.torrents = append(daemon.torrents[:index], daemon.torrents[index+1:]...)
+			daemon.removed = append(daemon.removed, removal{ id, daemon.now().Unix() })
+			return
+		}
+	}
+}
+
+func (daemon *Daemon) find(id int) *Torrent {
+	for _, torrent := range daemon.torrents {
+		if torrent.Id == id {
+			return torrent
+		}
+	}
+	return nil
+}
+
+func (daemon *Daemon) findHash(hash string) *Torrent {
+	for _, torrent := range daemon.torrents {
+		if torrent.HashString == hash {
+			return torrent
+		}
+	}
+	return nil
+}
+
+// Runs the transfers for the simulated time passed since the last tick.
+func (daemon *Daemon) tick() {
+	now := daemon.now()
+	seconds := now.Sub(daemon.lastTick).Seconds()
+	daemon.lastTick = now
+
+	if seconds <= 0 {
+		return
+	}
+
+	for _, torrent := range daemon.torrents {
+		simulate(torrent, seconds, now.Unix())
+	}
+
+	if daemon.demo != nil {
+		daemon.demo.step(daemon.torrents, seconds)
+	}
+}
+
+func simulate(torrent *Torrent, seconds float64, now int64) {
+	// Metadata arrives first, files become known only when it's complete.
+	// Like the real daemon, stopped torrents don't talk to peers at all.
+	if torrent.MetadataPercentComplete < 1 {
+		if torrent.Status == transmission.TR_STATUS_STOPPED {
+			return
+		}
+
+		torrent.MetadataPercentComplete += 0.1 * seconds
+		if torrent.MetadataPercentComplete >= 1 {
+			torrent.MetadataPercentComplete = 1
+			torrent.Files, torrent.pending = torrent.pending, nil
+		}
+		torrent.ActivityDate = now
+		return
+	}
+
+	if torrent.Status == transmission.TR_STATUS_DOWNLOAD {
+		budget := int64(float64(torrent.rateDownload()) * seconds)
+		for index := range torrent.Files {
+			file := &torrent.Files[index]
+			if !file.Wanted || budget == 0 {
+				continue
+			}
+
+			chunk := file.Length - file.BytesCompleted
+			if chunk > budget {
+				chunk = budget
+			}
+
+			file.BytesCompleted += chunk
+			torrent.DownloadedEver += chunk
+			budget -= chunk
+		}
+
+		if torrent.leftUntilDone() == 0 {
+			torrent.Status = transmission.TR_STATUS_SEED
+			torrent.DoneDate = now
+		}
+	}
+
+	if torrent.isActive() {
+		torrent.UploadedEver += int64(float64(torrent.rateUpload()) * seconds)
+		torrent.ActivityDate = now
+
+		for index := range torrent.Trackers {
+			tracker := &torrent.Trackers[index]
+			if now - tracker.LastAnnounce >= ANNOUNCE_INTERVAL {
+				tracker.LastAnnounce = now
+			}
+		}
+	}
+}
+
+func newSessionId() string {
+	data := make([]byte, 24)
+	rand.Read(data)
+	return hex.EncodeToString(data)
+}
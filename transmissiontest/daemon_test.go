@@ -0,0 +1,226 @@
+package transmissiontest
+
+import (
+	"errors"
+	"testing"
+	"time"
+	"transmission"
+)
+
+func TestSessionHandshake(t *testing.T) {
+	server := NewServer(nil)
+	defer server.Close()
+	client := server.Client()
+
+	// First request has no session ID and is replayed with the issued one.
+	if _, err := client.GetSessionSettings(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Rotated ID is picked up without an error.
+	server.Daemon.RotateSessionId()
+	if _, err := client.GetSessionSettings(); err != nil {
+		t.Fatal(err)
+	}
+
+	if calls := server.Daemon.Calls("session-get"); calls != 2 {
+		t.Fatalf("Expected 2 session-get calls, got %d", calls)
+	}
+}
+
+func TestAuthentication(t *testing.T) {
+	server := NewServer(nil)
+	defer server.Close()
+	server.Daemon.SetCredentials("user", "secret")
+
+	client := server.Client()
+	if _, err := client.GetSessionSettings(); !errors.Is(err, transmission.ErrUnauthorized) {
+		t.Fatalf("Expected ErrUnauthorized without credentials, got %v", err)
+	}
+
+	connection := server.Connection()
+	connection.Username, connection.Password = "user", "secret"
+	client, err := transmission.NewClient(connection)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.GetSessionSettings(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTorrentAddGetRemove(t *testing.T) {
+	server := NewServer(nil)
+	defer server.Close()
+	client := server.Client()
+
+	result, err := client.AddTorrent("/tmp/example.torrent", "/data")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Duplicate || result.Torrent.Id == 0 || result.Torrent.Name != "example" {
+		t.Fatalf("Unexpected add result: %+v", result)
+	}
+
+	torrents, err := client.TorrentGet([]int{ result.Torrent.Id }, "id", "name", "downloadDir", "files")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(torrents) != 1 {
+		t.Fatalf("Expected 1 torrent, got %d", len(torrents))
+	}
+
+	torrent := torrents[0]
+	if torrent.Name != "example" || torrent.DownloadDir != "/data" || len(torrent.Files) == 0 {
+		t.Fatalf("Unexpected torrent: %+v", torrent)
+	}
+
+	if err := client.Delete([]int{ torrent.Id }, false); err != nil {
+		t.Fatal(err)
+	}
+
+	torrents, err = client.TorrentGet(nil, "id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(torrents) != 0 {
+		t.Fatalf("Expected no torrents after removal, got %d", len(torrents))
+	}
+}
+
+func TestDuplicateAdd(t *testing.T) {
+	server := NewServer(nil)
+	defer server.Close()
+	client := server.Client()
+
+	sources := []string{
+		"/tmp/example.torrent",
+		"magnet:?xt=urn:btih:0123456789abcdef0123456789abcdef01234567&dn=example",
+	}
+
+	for _, source := range sources {
+		first, err := client.AddTorrent(source, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		second, err := client.AddTorrent(source, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if first.Duplicate || !second.Duplicate {
+			t.Fatalf("Expected only the second add of %s to be a duplicate: %+v, %+v", source, first, second)
+		}
+		if second.Torrent.Id != first.Torrent.Id || second.Torrent.HashString != first.Torrent.HashString {
+			t.Fatalf("Duplicate of %s doesn't point to the existing torrent: %+v, %+v", source, first, second)
+		}
+	}
+
+	if count := len(server.Daemon.Torrents()); count != len(sources) {
+		t.Fatalf("Expected %d torrents, got %d", len(sources), count)
+	}
+}
+
+func TestRecentlyActive(t *testing.T) {
+	server := NewServer(nil)
+	defer server.Close()
+	daemon, client := server.Daemon, server.Client()
+
+	// Stopped torrents with metadata don't change on their own.
+	files := []File{ File{ Name: "file", Length: 1024, Wanted: true } }
+	removed := daemon.Add(Torrent{ Name: "removed", Status: transmission.TR_STATUS_STOPPED, Files: files })
+	started := daemon.Add(Torrent{ Name: "started", Status: transmission.TR_STATUS_STOPPED, Files: files })
+	daemon.Add(Torrent{ Name: "idle", Status: transmission.TR_STATUS_STOPPED, Files: files })
+
+	// Added just now, so everything is recent.
+	torrents, _, err := client.RecentlyActive("id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(torrents) != 3 {
+		t.Fatalf("Expected 3 recently added torrents, got %d", len(torrents))
+	}
+
+	daemon.Advance(2 * RECENTLY_ACTIVE_SECONDS * time.Second)
+	daemon.Remove(removed)
+	if err := client.UpdateActive([]int{ started }, true); err != nil {
+		t.Fatal(err)
+	}
+
+	torrents, removedIds, err := client.RecentlyActive("id", "name")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(torrents) != 1 || torrents[0].Id != started {
+		t.Fatalf("Expected only the started torrent, got %+v", torrents)
+	}
+	if len(removedIds) != 1 || removedIds[0] != removed {
+		t.Fatalf("Expected removed ids [%d], got %v", removed, removedIds)
+	}
+
+	// Removal is forgotten once it's out of the window.
+	daemon.Advance(2 * RECENTLY_ACTIVE_SECONDS * time.Second)
+	_, removedIds, err = client.RecentlyActive("id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(removedIds) != 0 {
+		t.Fatalf("Expected no removed ids, got %v", removedIds)
+	}
+}
+
+func TestMagnetMetadata(t *testing.T) {
+	server := NewServer(nil)
+	defer server.Close()
+	daemon, client := server.Daemon, server.Client()
+
+	magnet := "magnet:?xt=urn:btih:0123456789abcdef0123456789abcdef01234567&dn=example"
+	result, err := client.AddTorrentWithOptions(magnet, transmission.AddOptions{ Paused: true })
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := []int{ result.Torrent.Id }
+
+	metadata := func() (float64, int) {
+		torrents, err := client.TorrentGet(ids, "id", "metadataPercentComplete", "files")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return torrents[0].MetadataPercentComplete, len(torrents[0].Files)
+	}
+
+	// Paused magnet has no peers to get metadata from.
+	daemon.Advance(20 * time.Second)
+	if percent, files := metadata(); percent != 0 || files != 0 {
+		t.Fatalf("Expected no metadata while stopped, got %.0f%% and %d files", percent * 100, files)
+	}
+
+	if err := client.UpdateActive(ids, true); err != nil {
+		t.Fatal(err)
+	}
+	daemon.Advance(20 * time.Second)
+	if percent, files := metadata(); percent != 1 || files == 0 {
+		t.Fatalf("Expected metadata once started, got %.0f%% and %d files", percent * 100, files)
+	}
+}
+
+func TestTorrentDetailsFields(t *testing.T) {
+	server := NewServer(nil)
+	defer server.Close()
+	client := server.Client()
+
+	id := server.Daemon.Add(jsonrpcTorrent())
+
+	// Only the requested tab is filled in.
+	details, err := client.TorrentDetails(id, transmission.DETAILS_TRACKERS_FIELDS...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if details.Name != "example" || len(details.Trackers) != 1 {
+		t.Errorf("Expected header and trackers, got %+v", details)
+	}
+	if len(details.Files) != 0 || len(details.Peers) != 0 {
+		t.Errorf("Expected no files and peers, got %+v", details)
+	}
+}
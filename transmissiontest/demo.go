@@ -0,0 +1,542 @@
+package transmissiontest
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+	"transmission"
+)
+
+/* Demo content */
+
+type demoFile struct {
+	name string
+	size int64
+}
+
+type demoTorrent struct {
+	name string
+	files []demoFile
+	labels []string
+	comment string
+	creator string
+	private bool
+}
+
+const (
+	KB = 1024
+	MB = 1024 * KB
+)
+
+// Freely distributable content, so screenshots don't advertise anything else.
+var demoTorrents = []demoTorrent{
+	{
+		"debian-12.7.0-amd64-netinst.iso",
+		[]demoFile{{ "debian-12.7.0-amd64-netinst.iso", 631 * MB }},
+		[]string{"linux"},
+		"\"Debian CD from cdimage.debian.org\"",
+		"mktorrent 1.1",
+		false,
+	},
+	{
+		"ubuntu-24.04.1-desktop-amd64.iso",
+		[]demoFile{{ "ubuntu-24.04.1-desktop-amd64.iso", 5916 * MB }},
+		[]string{"linux"},
+		"Ubuntu CD releases.ubuntu.com",
+		"mktorrent 1.1",
+		false,
+	},
+	{
+		"Fedora-Workstation-Live-x86_64-40",
+		[]demoFile{
+			{ "Fedora-Workstation-Live-x86_64-40/Fedora-Workstation-Live-x86_64-40-1.14.iso", 2190 * MB },
+			{ "Fedora-Workstation-Live-x86_64-40/Fedora-Workstation-40-1.14-x86_64-CHECKSUM", 1094 },
+		},
+		[]string{"linux"},
+		"",
+		"Transmission/4.0.5",
+		false,
+	},
+	{
+		"archlinux-2024.10.01-x86_64.iso",
+		[]demoFile{{ "archlinux-2024.10.01-x86_64.iso", 1152 * MB }},
+		[]string{"linux"},
+		"Arch Linux 2024.10.01 <https://archlinux.org>",
+		"mktorrent 1.1",
+		false,
+	},
+	{
+		"Big Buck Bunny (2008) [1080p]",
+		[]demoFile{
+			{ "Big Buck Bunny (2008) [1080p]/big_buck_bunny_1080p_h264.mkv", 886 * MB },
+			{ "Big Buck Bunny (2008) [1080p]/Subs/English.srt", 12 * KB },
+			{ "Big Buck Bunny (2008) [1080p]/Subs/Deutsch.srt", 13 * KB },
+			{ "Big Buck Bunny (2008) [1080p]/Sample/sample.mkv", 24 * MB },
+			{ "Big Buck Bunny (2008) [1080p]/poster.jpg", 412 * KB },
+			{ "Big Buck Bunny (2008) [1080p]/Big.Buck.Bunny.nfo", 3 * KB },
+		},
+		[]string{"movies", "blender"},
+		"Creative Commons Attribution 3.0",
+		"qBittorrent v4.6.7",
+		false,
+	},
+	{
+		"Sintel (2010) [2160p]",
+		[]demoFile{
+			{ "Sintel (2010) [2160p]/sintel_4k.mkv", 4107 * MB },
+			{ "Sintel (2010) [2160p]/Subs/English.srt", 7 * KB },
+			{ "Sintel (2010) [2160p]/Subs/Français.srt", 8 * KB },
+			{ "Sintel (2010) [2160p]/Subs/Español.srt", 8 * KB },
+			{ "Sintel (2010) [2160p]/Sample/sample.mkv", 58 * MB },
+			{ "Sintel (2010) [2160p]/Sintel.nfo", 2 * KB },
+		},
+		[]string{"movies", "blender"},
+		"Creative Commons Attribution 3.0",
+		"qBittorrent v4.6.7",
+		false,
+	},
+	{
+		"Tears of Steel (2012) [720p]",
+		[]demoFile{
+			{ "Tears of Steel (2012) [720p]/tears_of_steel_720p.mkv", 365 * MB },
+			{ "Tears of Steel (2012) [720p]/Subs/English.srt", 4 * KB },
+			{ "Tears of Steel (2012) [720p]/Subs/Nederlands.srt", 4 * KB },
+			{ "Tears of Steel (2012) [720p]/poster.png", 2 * MB },
+		},
+		[]string{"movies", "blender"},
+		"Creative Commons Attribution 3.0",
+		"Deluge 2.1.1",
+		false,
+	},
+	{
+		"Cosmos Laundromat (2015) [1080p]",
+		[]demoFile{
+			{ "Cosmos Laundromat (2015) [1080p]/cosmos_laundromat_1080p.mp4", 1221 * MB },
+			{ "Cosmos Laundromat (2015) [1080p]/Subs/English.srt", 6 * KB },
+			{ "Cosmos Laundromat (2015) [1080p]/Cosmos.Laundromat.nfo", 2 * KB },
+		},
+		[]string{"movies", "blender"},
+		"",
+		"Transmission/3.00",
+		false,
+	},
+	{
+		"Kevin MacLeod - Public Domain Selections (2019) [FLAC]",
+		demoAlbum(
+			"Kevin MacLeod - Public Domain Selections (2019) [FLAC]",
+			"flac",
+			[]string{
+				"Sneaky Snitch", "Monkeys Spinning Monkeys", "Fluffing a Duck",
+				"Carefree", "Wallpaper", "Local Forecast", "Investigations",
+				"Scheming Weasel", "Merry Go", "Pixel Peeker Polka",
+			},
+			24 * MB,
+		),
+		[]string{"music"},
+		"",
+		"Transmission/4.0.6",
+		false,
+	},
+	{
+		"Free Music Archive - Electronic Sampler Vol. 3 [MP3 320]",
+		demoAlbum(
+			"Free Music Archive - Electronic Sampler Vol. 3 [MP3 320]",
+			"mp3",
+			[]string{
+				"Night Owl", "Enthusiast", "Algorithms", "Starling",
+				"Tabula Rasa", "Heartbeat", "Sunrise Drive", "Elevator Pitch",
+			},
+			9 * MB,
+		),
+		[]string{"music"},
+		"",
+		"qBittorrent v4.5.2",
+		false,
+	},
+	{
+		"wikipedia_en_all_maxi_2024-01.zim",
+		[]demoFile{{ "wikipedia_en_all_maxi_2024-01.zim", 104448 * MB }},
+		[]string{"archive"},
+		"Kiwix offline Wikipedia",
+		"mktorrent 1.1",
+		false,
+	},
+	{
+		"openstreetmap-planet-241007.osm.pbf",
+		[]demoFile{{ "openstreetmap-planet-241007.osm.pbf", 77824 * MB }},
+		[]string{"datasets"},
+		"OpenStreetMap data is (c) OpenStreetMap contributors, ODbL",
+		"mktorrent 1.1",
+		false,
+	},
+	{
+		"imagenet-sample-validation",
+		[]demoFile{
+			{ "imagenet-sample-validation/LICENSE", 4 * KB },
+			{ "imagenet-sample-validation/README.md", 9 * KB },
+			{ "imagenet-sample-validation/val/images-00.tar", 1024 * MB },
+			{ "imagenet-sample-validation/val/images-01.tar", 1024 * MB },
+			{ "imagenet-sample-validation/val/images-02.tar", 1024 * MB },
+			{ "imagenet-sample-validation/val/images-03.tar", 611 * MB },
+			{ "imagenet-sample-validation/val/labels.csv", 1536 * KB },
+		},
+		[]string{"datasets"},
+		"",
+		"Transmission/4.0.6",
+		true,
+	},
+	{
+		"LibreOffice_24.8.2_Linux_x86-64_deb",
+		[]demoFile{
+			{ "LibreOffice_24.8.2_Linux_x86-64_deb/LibreOffice_24.8.2_Linux_x86-64_deb.tar.gz", 214 * MB },
+			{ "LibreOffice_24.8.2_Linux_x86-64_deb/LibreOffice_24.8.2_Linux_x86-64_deb_helppack_en-US.tar.gz", 4 * MB },
+		},
+		[]string{},
+		"",
+		"mktorrent 1.1",
+		false,
+	},
+	{
+		"The Internet's Own Boy (2014) [CC BY-NC-SA]",
+		[]demoFile{
+			{ "The Internet's Own Boy (2014) [CC BY-NC-SA]/The.Internets.Own.Boy.2014.mp4", 1402 * MB },
+			{ "The Internet's Own Boy (2014) [CC BY-NC-SA]/The.Internets.Own.Boy.2014.en.srt", 118 * KB },
+		},
+		[]string{"movies"},
+		"",
+		"Transmission/2.94",
+		false,
+	},
+}
+
+func demoAlbum(name string, extension string, titles []string, trackSize int64) []demoFile {
+	files := make([]demoFile, 0, len(titles) + 1)
+	for index, title := range titles {
+		files = append(files, demoFile{
+			fmt.Sprintf("%s/%02d - %s.%s", name, index + 1, title, extension),
+			trackSize + int64(index % 4) * trackSize / 5,
+		})
+	}
+	return append(files, demoFile{ name + "/cover.jpg", 640 * KB })
+}
+
+var demoClients = []string{
+	"Transmission 4.0.6",
+	"Transmission 3.00",
+	"qBittorrent 4.6.7",
+	"qBittorrent 5.0.0",
+	"µTorrent 3.6.0",
+	"Deluge 2.1.1",
+	"libtorrent (Rasterbar) 2.0.10",
+	"BiglyBT 3.6.0.0",
+	"aria2/1.37.0",
+	"KTorrent 23.08.5",
+}
+
+// Discovery sources, weighted roughly the way a public swarm looks.
+var demoSources = []string{
+	PEER_FROM_TRACKER, PEER_FROM_TRACKER, PEER_FROM_TRACKER, PEER_FROM_TRACKER,
+	PEER_FROM_DHT, PEER_FROM_DHT, PEER_FROM_DHT,
+	PEER_FROM_PEX, PEER_FROM_PEX,
+	PEER_FROM_INCOMING,
+	PEER_FROM_LPD,
+}
+
+// Reserved example domains, so screenshots don't point at real trackers.
+var demoTrackers = []string{
+	"udp://tracker.example.org:1337/announce",
+	"https://torrent.example.com/announce",
+	"http://bt.example.net:6969/announce",
+	"udp://open.example.org:80/announce",
+}
+
+/* Demo daemon */
+
+// Random source driving the demo liveliness: fluctuating speeds, peers coming
+// and going, queued torrents starting.
+type demo struct {
+	random *rand.Rand
+}
+
+// Daemon filled with plausible torrents, for demos and screenshots. Same seed
+// gives the same initial set of torrents.
+func NewDemoDaemon(seed int64) *Daemon {
+	daemon := NewDaemon()
+	daemon.demo = &demo{ rand.New(rand.NewSource(seed)) }
+
+	now := daemon.now()
+	for _, template := range demoTorrents {
+		daemon.add(daemon.demo.torrent(template, now))
+	}
+
+	return daemon
+}
+
+func (demo *demo) torrent(template demoTorrent, now time.Time) Torrent {
+	random := demo.random
+
+	torrent := Torrent{
+		Name: template.name,
+		Comment: template.comment,
+		Creator: template.creator,
+		IsPrivate: template.private,
+		Labels: append([]string{}, template.labels...),
+		PeerLimit: 50,
+		AddedDate: now.Add(-time.Duration(1 + random.Intn(60 * 24 * 30)) * time.Minute).Unix(),
+	}
+
+	for _, file := range template.files {
+		torrent.Files = append(torrent.Files, File{
+			Name: file.name,
+			Length: file.size,
+			Wanted: true,
+			Priority: transmission.TR_PRIORITY_NORMAL,
+		})
+	}
+
+	// Skip samples and nfo files, like people usually do.
+	for index := range torrent.Files {
+		name := strings.ToLower(torrent.Files[index].Name)
+		if strings.Contains(name, "/sample/") || strings.HasSuffix(name, ".nfo") {
+			torrent.Files[index].Wanted = false
+		}
+		if strings.HasSuffix(name, ".srt") {
+			torrent.Files[index].Priority = transmission.TR_PRIORITY_HIGH
+		}
+	}
+
+	var progress float64
+	switch roll := random.Intn(20); {
+	case roll < 8:
+		torrent.Status, progress = transmission.TR_STATUS_SEED, 1
+	case roll < 14:
+		torrent.Status, progress = transmission.TR_STATUS_DOWNLOAD, 0.05 + random.Float64() * 0.9
+	case roll < 16:
+		torrent.Status, progress = transmission.TR_STATUS_DOWNLOAD_WAIT, random.Float64() * 0.2
+	case roll < 17:
+		torrent.Status, progress = transmission.TR_STATUS_CHECK, random.Float64()
+	default:
+		torrent.Status, progress = transmission.TR_STATUS_STOPPED, random.Float64()
+	}
+
+	if progress == 1 || random.Intn(3) == 0 {
+		torrent.BandwidthPriority = random.Intn(3) - 1
+	}
+
+	for index := range torrent.Files {
+		file := &torrent.Files[index]
+		if file.Wanted {
+			file.BytesCompleted = int64(float64(file.Length) * progress)
+		}
+	}
+
+	torrent.DownloadedEver = torrent.haveValid()
+	torrent.UploadedEver = int64(float64(torrent.DownloadedEver) * random.Float64() * 3)
+	if progress == 1 {
+		torrent.DoneDate = torrent.AddedDate + int64(600 + random.Intn(3 * 3600))
+	}
+
+	torrent.DownloadSpeed = int64(200 * KB + random.Intn(8 * MB))
+	torrent.UploadSpeed = int64(10 * KB + random.Intn(MB))
+	torrent.baseDownload, torrent.baseUpload = torrent.DownloadSpeed, torrent.UploadSpeed
+
+	if torrent.isActive() {
+		count := 3 + random.Intn(25)
+		for index := 0; index < count; index++ {
+			torrent.Peers = append(torrent.Peers, demo.peer(&torrent))
+		}
+	}
+
+	demo.trackers(&torrent, now)
+
+	// Stopped torrents sometimes carry a tracker error.
+	if torrent.Status == transmission.TR_STATUS_STOPPED && random.Intn(2) == 0 {
+		torrent.Error = 2
+		torrent.ErrorString = "Tracker gave HTTP response code 404 (Not Found)"
+		torrent.Trackers[0].AnnounceError = torrent.ErrorString
+	}
+
+	return torrent
+}
+
+// One to three trackers, some of them in a backup tier, with an occasional
+// failing scrape.
+func (demo *demo) trackers(torrent *Torrent, now time.Time) {
+	random := demo.random
+
+	count := 1 + random.Intn(3)
+	hosts := random.Perm(len(demoTrackers))[:count]
+
+	tiers := [][]string{}
+	for index, host := range hosts {
+		if index == 0 || random.Intn(2) == 0 {
+			tiers = append(tiers, []string{})
+		}
+		tiers[len(tiers) - 1] = append(tiers[len(tiers) - 1], demoTrackers[host])
+	}
+	torrent.setTrackers(tiers)
+
+	for index := range torrent.Trackers {
+		tracker := &torrent.Trackers[index]
+		tracker.LastAnnounce = now.Add(-time.Duration(random.Intn(ANNOUNCE_INTERVAL)) * time.Second).Unix()
+		tracker.Seeders = random.Intn(2000)
+		tracker.Leechers = random.Intn(300)
+		if random.Intn(6) == 0 {
+			tracker.Seeders, tracker.Leechers = -1, -1
+			tracker.ScrapeError = "Could not connect to tracker"
+		}
+	}
+}
+
+// Addresses are taken from documentation ranges (RFC 5737, RFC 3849), so
+// screenshots never show anyone's real IP.
+func (demo *demo) peer(torrent *Torrent) Peer {
+	random := demo.random
+
+	var address string
+	if random.Intn(5) == 0 {
+		address = fmt.Sprintf("2001:db8:%x:%x::%x", random.Intn(0x10000), random.Intn(0x10000), 1 + random.Intn(0xffff))
+	} else {
+		networks := []string{"192.0.2", "198.51.100", "203.0.113"}
+		address = fmt.Sprintf("%s.%d", networks[random.Intn(len(networks))], 1 + random.Intn(254))
+	}
+
+	// Seeders are only interesting while downloading.
+	progress := random.Float64() * 0.99
+	if torrent.Status == transmission.TR_STATUS_DOWNLOAD && random.Intn(5) < 2 {
+		progress = 1
+	}
+
+	source := demoSources[random.Intn(len(demoSources))]
+
+	return Peer{
+		Address: address,
+		Port: 1024 + random.Intn(64511),
+		ClientName: demoClients[random.Intn(len(demoClients))],
+		Progress: progress,
+		IsEncrypted: random.Intn(5) < 3,
+		IsUTP: random.Intn(2) == 0,
+		IsIncoming: source == PEER_FROM_INCOMING,
+		Source: source,
+	}
+}
+
+// Runs after the transfer simulation on every tick.
+func (demo *demo) step(torrents []*Torrent, seconds float64) {
+	random := demo.random
+
+	for _, torrent := range torrents {
+		// Queue and verification move along on their own.
+		switch torrent.Status {
+		case transmission.TR_STATUS_DOWNLOAD_WAIT:
+			if random.Float64() < 0.02 * seconds {
+				torrent.Status = transmission.TR_STATUS_DOWNLOAD
+			}
+		case transmission.TR_STATUS_CHECK_WAIT:
+			if random.Float64() < 0.2 * seconds {
+				torrent.Status = transmission.TR_STATUS_CHECK
+			}
+		case transmission.TR_STATUS_CHECK:
+			if random.Float64() < 0.05 * seconds {
+				torrent.Status = transmission.TR_STATUS_DOWNLOAD
+				if torrent.leftUntilDone() == 0 {
+					torrent.Status = transmission.TR_STATUS_SEED
+				}
+			}
+		}
+
+		if !torrent.isActive() {
+			torrent.Peers = nil
+			continue
+		}
+
+		// Torrents added over RPC fluctuate around their initial speeds.
+		if torrent.baseDownload == 0 && torrent.baseUpload == 0 {
+			torrent.baseDownload, torrent.baseUpload = torrent.DownloadSpeed, torrent.UploadSpeed
+		}
+
+		weight := seconds / 4
+		if weight > 1 {
+			weight = 1
+		}
+		torrent.DownloadSpeed += int64(float64(demo.jitter(torrent.baseDownload) - torrent.DownloadSpeed) * weight)
+		torrent.UploadSpeed += int64(float64(demo.jitter(torrent.baseUpload) - torrent.UploadSpeed) * weight)
+
+		demo.churn(torrent, seconds)
+		demo.spread(torrent, seconds)
+	}
+}
+
+func (demo *demo) jitter(base int64) int64 {
+	return int64(float64(base) * (0.4 + demo.random.Float64() * 1.2))
+}
+
+// Peers drop off and new ones connect.
+func (demo *demo) churn(torrent *Torrent, seconds float64) {
+	random := demo.random
+
+	limit := torrent.PeerLimit
+	if limit == 0 || limit > 30 {
+		limit = 30
+	}
+
+	if len(torrent.Peers) > 0 && random.Float64() < 0.1 * seconds {
+		index := random.Intn(len(torrent.Peers))
+		torrent.Peers = append(torrent.Peers[:index], torrent.Peers[index+1:]...)
+	}
+
+	if len(torrent.Peers) < limit && random.Float64() < 0.15 * seconds {
+		torrent.Peers = append(torrent.Peers, demo.peer(torrent))
+	}
+
+	// Nobody needs a seed once the download is done.
+	if torrent.Status == transmission.TR_STATUS_SEED {
+		peers := torrent.Peers[:0]
+		for _, peer := range torrent.Peers {
+			if peer.Progress < 1 {
+				peers = append(peers, peer)
+			}
+		}
+		torrent.Peers = peers
+	}
+}
+
+// Splits torrent's transfer rates between peers, so they add up to the totals.
+func (demo *demo) spread(torrent *Torrent, seconds float64) {
+	random := demo.random
+
+	download, upload := torrent.rateDownload(), torrent.rateUpload()
+
+	var downloadWeight, uploadWeight float64
+	weights := make([]float64, len(torrent.Peers))
+	for index, peer := range torrent.Peers {
+		weights[index] = random.Float64()
+		if peer.Progress > 0 {
+			downloadWeight += weights[index]
+		}
+		if peer.Progress < 1 {
+			uploadWeight += weights[index]
+		}
+	}
+
+	for index := range torrent.Peers {
+		peer := &torrent.Peers[index]
+		peer.RateToClient, peer.RateToPeer = 0, 0
+
+		if peer.Progress > 0 && downloadWeight > 0 {
+			peer.RateToClient = int64(float64(download) * weights[index] / downloadWeight)
+		}
+		if peer.Progress < 1 && uploadWeight > 0 {
+			peer.RateToPeer = int64(float64(upload) * weights[index] / uploadWeight)
+		}
+
+		// Other leechers are downloading too.
+		if peer.Progress < 1 {
+			peer.Progress += random.Float64() * 0.002 * seconds
+			if peer.Progress > 0.999 {
+				peer.Progress = 0.999
+			}
+		}
+	}
+}
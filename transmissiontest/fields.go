@@ -0,0 +1,271 @@
+package transmissiontest
+
+import (
+	"strings"
+	"transmission"
+)
+
+/* torrent-get fields */
+
+type fieldGetter func(torrent *Torrent) interface{}
+
+// Fields the fake daemon knows about. Unknown fields are silently skipped,
+// the same way the real daemon does it.
+var torrentFields = map[string]fieldGetter{
+	"id": func(t *Torrent) interface{} { return t.Id },
+	"hashString": func(t *Torrent) interface{} { return t.HashString },
+	"name": func(t *Torrent) interface{} { return t.Name },
+	"downloadDir": func(t *Torrent) interface{} { return t.DownloadDir },
+	"status": func(t *Torrent) interface{} { return t.Status },
+	"error": func(t *Torrent) interface{} { return t.Error },
+	"errorString": func(t *Torrent) interface{} { return t.ErrorString },
+	"comment": func(t *Torrent) interface{} { return t.Comment },
+	"creator": func(t *Torrent) interface{} { return t.Creator },
+	"isPrivate": func(t *Torrent) interface{} { return t.IsPrivate },
+	"labels": func(t *Torrent) interface{} { return nonNilStrings(t.Labels) },
+	"bandwidthPriority": func(t *Torrent) interface{} { return t.BandwidthPriority },
+	"peer-limit": func(t *Torrent) interface{} { return t.PeerLimit },
+	"file-count": func(t *Torrent) interface{} { return len(t.Files) },
+	"addedDate": func(t *Torrent) interface{} { return t.AddedDate },
+	"doneDate": func(t *Torrent) interface{} { return t.DoneDate },
+	"activityDate": func(t *Torrent) interface{} { return t.ActivityDate },
+	"totalSize": func(t *Torrent) interface{} { return t.totalSize() },
+	"sizeWhenDone": func(t *Torrent) interface{} { return t.sizeWhenDone() },
+	"leftUntilDone": func(t *Torrent) interface{} { return t.leftUntilDone() },
+	"haveValid": func(t *Torrent) interface{} { return t.haveValid() },
+	"percentDone": func(t *Torrent) interface{} { return t.percentDone() },
+	"metadataPercentComplete": func(t *Torrent) interface{} { return t.MetadataPercentComplete },
+	"eta": func(t *Torrent) interface{} { return t.eta() },
+	"rateDownload": func(t *Torrent) interface{} { return t.rateDownload() },
+	"rateUpload": func(t *Torrent) interface{} { return t.rateUpload() },
+	"uploadRatio": func(t *Torrent) interface{} { return t.uploadRatio() },
+	"uploadedEver": func(t *Torrent) interface{} { return t.UploadedEver },
+	"downloadedEver": func(t *Torrent) interface{} { return t.DownloadedEver },
+	"peersConnected": func(t *Torrent) interface{} { return t.peersConnected() },
+	"peers": func(t *Torrent) interface{} {
+		peers := t.activePeers()
+		output := make([]map[string]interface{}, len(peers))
+		for index, peer := range peers {
+			output[index] = map[string]interface{}{
+				"address": peer.Address,
+				"port": peer.Port,
+				"clientName": peer.ClientName,
+				"flagStr": peerFlags(peer),
+				"progress": peer.Progress,
+				"rateToClient": peer.RateToClient,
+				"rateToPeer": peer.RateToPeer,
+				"isEncrypted": peer.IsEncrypted,
+				"isUTP": peer.IsUTP,
+				"isIncoming": peer.IsIncoming,
+				"isDownloadingFrom": peer.RateToClient > 0,
+				"isUploadingTo": peer.RateToPeer > 0,
+				"clientIsChoked": peer.RateToPeer == 0,
+				"clientIsInterested": peer.Progress < 1,
+				"peerIsChoked": peer.RateToClient == 0,
+				"peerIsInterested": peer.RateToPeer > 0,
+			}
+		}
+		return output
+	},
+	"peersFrom": func(t *Torrent) interface{} {
+		counts := map[string]int{}
+		for _, peer := range t.activePeers() {
+			counts[peer.Source] += 1
+		}
+		return map[string]interface{}{
+			"fromCache": counts[PEER_FROM_CACHE],
+			"fromDht": counts[PEER_FROM_DHT],
+			"fromIncoming": counts[PEER_FROM_INCOMING],
+			"fromLpd": counts[PEER_FROM_LPD],
+			"fromLtep": counts[PEER_FROM_LTEP],
+			"fromPex": counts[PEER_FROM_PEX],
+			"fromTracker": counts[PEER_FROM_TRACKER],
+		}
+	},
+	"trackers": func(t *Torrent) interface{} {
+		trackers := make([]map[string]interface{}, len(t.Trackers))
+		for index, tracker := range t.Trackers {
+			trackers[index] = map[string]interface{}{
+				"id": tracker.Id,
+				"announce": tracker.Announce,
+				"scrape": strings.Replace(tracker.Announce, "/announce", "/scrape", 1),
+				"sitename": trackerSitename(tracker.Announce),
+				"tier": tracker.Tier,
+			}
+		}
+		return trackers
+	},
+	"trackerList": func(t *Torrent) interface{} {
+		return transmission.FormatTrackerList(t.trackerTiers())
+	},
+	"trackerStats": func(t *Torrent) interface{} {
+		stats := make([]map[string]interface{}, len(t.Trackers))
+		for index, tracker := range t.Trackers {
+			// Only running torrents announce.
+			var announceState int
+			var nextAnnounce int64
+			if t.isActive() {
+				announceState, nextAnnounce = transmission.TR_TRACKER_WAITING, tracker.LastAnnounce + ANNOUNCE_INTERVAL
+			}
+
+			announced := tracker.LastAnnounce > 0
+			peers := 0
+			if tracker.Seeders > 0 {
+				peers += tracker.Seeders
+			}
+			if tracker.Leechers > 0 {
+				peers += tracker.Leechers
+			}
+			announceResult, scrapeResult := "Success", "Success"
+			if tracker.AnnounceError != "" {
+				announceResult = tracker.AnnounceError
+			}
+			if tracker.ScrapeError != "" {
+				scrapeResult = tracker.ScrapeError
+			}
+
+			stats[index] = map[string]interface{}{
+				"id": tracker.Id,
+				"announce": tracker.Announce,
+				"scrape": strings.Replace(tracker.Announce, "/announce", "/scrape", 1),
+				"host": trackerHost(tracker.Announce),
+				"sitename": trackerSitename(tracker.Announce),
+				"tier": tracker.Tier,
+				"isBackup": false,
+				"announceState": announceState,
+				"scrapeState": announceState,
+				"hasAnnounced": announced,
+				"hasScraped": announced,
+				"lastAnnounceTime": tracker.LastAnnounce,
+				"lastAnnounceStartTime": tracker.LastAnnounce,
+				"lastAnnounceSucceeded": announced && tracker.AnnounceError == "",
+				"lastAnnounceTimedOut": false,
+				"lastAnnounceResult": announceResult,
+				"lastAnnouncePeerCount": peers,
+				"lastScrapeTime": tracker.LastAnnounce,
+				"lastScrapeStartTime": tracker.LastAnnounce,
+				"lastScrapeSucceeded": announced && tracker.ScrapeError == "",
+				"lastScrapeTimedOut": 0,
+				"lastScrapeResult": scrapeResult,
+				"nextAnnounceTime": nextAnnounce,
+				"nextScrapeTime": nextAnnounce,
+				"seederCount": tracker.Seeders,
+				"leecherCount": tracker.Leechers,
+				"downloadCount": -1,
+			}
+		}
+		return stats
+	},
+	"downloadLimit": func(t *Torrent) interface{} { return t.DownloadLimit },
+	"downloadLimited": func(t *Torrent) interface{} { return t.DownloadLimited },
+	"uploadLimit": func(t *Torrent) interface{} { return t.UploadLimit },
+	"uploadLimited": func(t *Torrent) interface{} { return t.UploadLimited },
+	"files": func(t *Torrent) interface{} {
+		files := make([]map[string]interface{}, len(t.Files))
+		for index, file := range t.Files {
+			files[index] = map[string]interface{}{
+				"name": file.Name,
+				"length": file.Length,
+				"bytesCompleted": file.BytesCompleted,
+			}
+		}
+		return files
+	},
+	"fileStats": func(t *Torrent) interface{} {
+		stats := make([]map[string]interface{}, len(t.Files))
+		for index, file := range t.Files {
+			stats[index] = map[string]interface{}{
+				"bytesCompleted": file.BytesCompleted,
+				"wanted": file.Wanted,
+				"priority": file.Priority,
+			}
+		}
+		return stats
+	},
+	"priorities": func(t *Torrent) interface{} {
+		priorities := make([]int, len(t.Files))
+		for index, file := range t.Files {
+			priorities[index] = file.Priority
+		}
+		return priorities
+	},
+	"wanted": func(t *Torrent) interface{} {
+		wanted := make([]int, len(t.Files))
+		for index, file := range t.Files {
+			if file.Wanted {
+				wanted[index] = 1
+			}
+		}
+		return wanted
+	},
+}
+
+func torrentObject(torrent *Torrent, fields []string) map[string]interface{} {
+	object := make(map[string]interface{}, len(fields))
+	for _, field := range fields {
+		if getter, ok := torrentFields[field]; ok {
+			object[field] = getter(torrent)
+		}
+	}
+	return object
+}
+
+// Header row with known fields, followed by a row of values per torrent.
+func torrentTable(torrents []*Torrent, fields []string) [][]interface{} {
+	header := []interface{}{}
+	getters := []fieldGetter{}
+	for _, field := range fields {
+		if getter, ok := torrentFields[field]; ok {
+			header = append(header, field)
+			getters = append(getters, getter)
+		}
+	}
+
+	table := [][]interface{}{ header }
+	for _, torrent := range torrents {
+		row := make([]interface{}, len(getters))
+		for index, getter := range getters {
+			row[index] = getter(torrent)
+		}
+		table = append(table, row)
+	}
+	return table
+}
+
+func nonNilStrings(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
+
+// Flag letters the way the daemon composes them.
+func peerFlags(peer Peer) string {
+	flags := ""
+	if peer.RateToClient > 0 {
+		flags += "D"
+	} else if peer.Progress > 0 {
+		flags += "d"
+	}
+	if peer.RateToPeer > 0 {
+		flags += "U"
+	} else {
+		flags += "u"
+	}
+	if peer.IsEncrypted {
+		flags += "E"
+	}
+	switch peer.Source {
+	case PEER_FROM_PEX:
+		flags += "X"
+	case PEER_FROM_DHT:
+		flags += "H"
+	}
+	if peer.IsIncoming {
+		flags += "I"
+	}
+	if peer.IsUTP {
+		flags += "T"
+	}
+	return flags
+}
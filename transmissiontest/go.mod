@@ -0,0 +1,8 @@
+module transmissiontest
+
+go 1.13
+
+require (
+    transmission v0.0.0
+    metainfo v0.0.0
+)
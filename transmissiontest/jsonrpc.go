@@ -0,0 +1,181 @@
+package transmissiontest
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"transmission"
+)
+
+/* JSON-RPC 2.0 */
+
+// Served only when 'rpc-version' is high enough, like the real daemon does.
+
+const (
+	JSONRPC_METHOD_NOT_FOUND = -32601
+	JSONRPC_SERVER_ERROR = -32000
+)
+
+// Legacy argument names handled by the methods, besides fields and settings.
+var argumentNames = []string{
+	"ids", "fields", "format", "filename", "metainfo", "download-dir", "paused",
+	"labels", "location", "move", "delete-local-data", "files-wanted",
+	"files-unwanted", "priority-high", "priority-low", "priority-normal",
+	"bandwidthPriority", "peer-limit", "downloadLimit", "downloadLimited",
+	"uploadLimit", "uploadLimited", "recently-active", "trackerAdd",
+	"trackerRemove", "trackerReplace", "trackerList",
+}
+
+type jsonrpcRequest struct {
+	Version string								`json:"jsonrpc"`
+	Method string									`json:"method"`
+	Params map[string]interface{} `json:"params"`
+	Id interface{}								`json:"id"`
+}
+
+func isJSONRPC(body []byte) bool {
+	var probe struct {
+		Version string `json:"jsonrpc"`
+	}
+	return json.Unmarshal(body, &probe) == nil && probe.Version == "2.0"
+}
+
+// Nil if the request can't be parsed.
+func (daemon *Daemon) serveJSONRPC(body []byte) interface{} {
+	var rpc jsonrpcRequest
+	if err := json.Unmarshal(body, &rpc); err != nil {
+		return nil
+	}
+
+	method := strings.Replace(rpc.Method, "_", "-", -1)
+	daemon.jsonrpcCalls += 1
+	output, result := daemon.call(method, daemon.legacyParams(method, rpc.Params))
+
+	if result != "success" {
+		code := JSONRPC_SERVER_ERROR
+		if _, ok := methods[method]; !ok {
+			code = JSONRPC_METHOD_NOT_FOUND
+		}
+
+		return map[string]interface{}{
+			"jsonrpc": "2.0",
+			"id": rpc.Id,
+			"error": map[string]interface{}{
+				"code": code,
+				"message": result,
+			},
+		}
+	}
+
+	return map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id": rpc.Id,
+		"result": snakeCaseOutput(output),
+	}
+}
+
+// Snake case to legacy name mapping.
+func legacyNames(names []string) map[string]string {
+	output := make(map[string]string, len(names))
+	for _, name := range names {
+		output[transmission.SnakeCase(name)] = name
+	}
+	return output
+}
+
+// Torrent fields and session settings both have 'download-dir', but spelled
+// differently, so field names are resolved per method.
+func (daemon *Daemon) legacyParams(method string, params map[string]interface{}) map[string]interface{} {
+	sessionNames := []string{ "session-id" }
+	for name := range daemon.session {
+		sessionNames = append(sessionNames, name)
+	}
+
+	torrentNames := []string{}
+	for name := range torrentFields {
+		torrentNames = append(torrentNames, name)
+	}
+
+	arguments := legacyNames(argumentNames)
+	fields := legacyNames(torrentNames)
+	if strings.HasPrefix(method, "session-") {
+		arguments = legacyNames(append(sessionNames, argumentNames...))
+		fields = legacyNames(sessionNames)
+	}
+
+	output := make(map[string]interface{}, len(params))
+	for key, value := range params {
+		if legacy, ok := arguments[key]; ok {
+			key = legacy
+		}
+
+		switch key {
+		case "fields":
+			list := []interface{}{}
+			for _, field := range stringList(value) {
+				if legacy, ok := fields[field]; ok {
+					field = legacy
+				}
+				list = append(list, field)
+			}
+			value = list
+		case "ids":
+			if name, ok := value.(string); ok {
+				if legacy, ok := arguments[name]; ok {
+					value = legacy
+				}
+			}
+		}
+
+		output[key] = value
+	}
+	return output
+}
+
+// Renames all keys to snake case, including the header row of table-formatted
+// torrent lists.
+func snakeCaseOutput(output map[string]interface{}) interface{} {
+	data, err := json.Marshal(output)
+	if err != nil {
+		return output
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var generic interface{}
+	if decoder.Decode(&generic) != nil {
+		return output
+	}
+	return snakeCaseKeys(generic)
+}
+
+func snakeCaseKeys(value interface{}) interface{} {
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		output := make(map[string]interface{}, len(typed))
+		for key, item := range typed {
+			if rows, ok := item.([]interface{}); ok && key == "torrents" && len(rows) > 0 {
+				if header, ok := rows[0].([]interface{}); ok {
+					renamed := make([]interface{}, len(header))
+					for index, cell := range header {
+						if name, ok := cell.(string); ok {
+							cell = transmission.SnakeCase(name)
+						}
+						renamed[index] = cell
+					}
+					item = append([]interface{}{ renamed }, rows[1:]...)
+				}
+			}
+			output[transmission.SnakeCase(key)] = snakeCaseKeys(item)
+		}
+		return output
+	case []interface{}:
+		output := make([]interface{}, len(typed))
+		for index, item := range typed {
+			output[index] = snakeCaseKeys(item)
+		}
+		return output
+	}
+	return value
+}
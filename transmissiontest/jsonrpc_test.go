@@ -0,0 +1,211 @@
+package transmissiontest
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"transmission"
+)
+
+// Fields with hyphenated legacy names, and nested objects.
+var jsonrpcFields = []string{
+	"id", "name", "status", "downloadDir", "bandwidthPriority", "peer-limit",
+	"file-count", "files", "fileStats", "peers", "peersFrom", "trackerStats",
+}
+
+func jsonrpcTorrent() Torrent {
+	return Torrent{
+		Name: "example",
+		Status: transmission.TR_STATUS_DOWNLOAD,
+		BandwidthPriority: transmission.TR_PRIORITY_HIGH,
+		PeerLimit: 42,
+		Files: []File{
+			File{ Name: "example/a", Length: 1000, BytesCompleted: 500, Wanted: true },
+			File{ Name: "example/b", Length: 2000, Priority: transmission.TR_PRIORITY_LOW },
+		},
+		Peers: []Peer{
+			Peer{ Address: "10.0.0.1", Port: 6881, ClientName: "Peer", RateToClient: 1024, IsUTP: true, Source: PEER_FROM_DHT },
+		},
+		Trackers: []Tracker{
+			Tracker{ Id: 1, Announce: "http://tracker.example.org/announce", LastAnnounce: 1000, Seeders: 3 },
+		},
+	}
+}
+
+// Checks values that only survive if names are mapped back at every level.
+func checkJSONRPCTorrent(t *testing.T, torrent transmission.Torrent) {
+	if torrent.Name != "example" || torrent.DownloadDir != DEFAULT_DOWNLOAD_DIR {
+		t.Errorf("Unexpected name and location: %q, %q", torrent.Name, torrent.DownloadDir)
+	}
+	if torrent.PeerLimit != 42 || torrent.FileCount != 2 || torrent.BandwidthPriority != transmission.TR_PRIORITY_HIGH {
+		t.Errorf(
+			"Unexpected peer limit %d, file count %d, priority %d",
+			torrent.PeerLimit, torrent.FileCount, torrent.BandwidthPriority)
+	}
+	if len(torrent.Files) != 2 || torrent.Files[0].BytesCompleted != 500 || torrent.Files[1].Name != "example/b" {
+		t.Errorf("Unexpected files: %+v", torrent.Files)
+	}
+	if len(torrent.FileStats) != 2 || !torrent.FileStats[0].Wanted || torrent.FileStats[1].Priority != transmission.TR_PRIORITY_LOW {
+		t.Errorf("Unexpected file stats: %+v", torrent.FileStats)
+	}
+	if len(torrent.Peers) != 1 || !torrent.Peers[0].IsUTP || torrent.Peers[0].RateToClient != 1024 || torrent.Peers[0].ClientName != "Peer" {
+		t.Errorf("Unexpected peers: %+v", torrent.Peers)
+	}
+	if torrent.PeersFrom.FromDht != 1 {
+		t.Errorf("Unexpected peer sources: %+v", torrent.PeersFrom)
+	}
+	if len(torrent.TrackerStats) != 1 || !torrent.TrackerStats[0].HasAnnounced || torrent.TrackerStats[0].SeederCount != 3 {
+		t.Errorf("Unexpected tracker stats: %+v", torrent.TrackerStats)
+	}
+}
+
+func jsonrpcServer(t *testing.T) (*Server, *transmission.Client) {
+	server := NewServer(nil)
+	server.Daemon.SetSession("rpc-version", transmission.FEATURE_JSON_RPC.Version())
+
+	client := server.Client()
+	if _, err := client.Capabilities(); err != nil {
+		server.Close()
+		t.Fatal(err)
+	}
+	if !client.Supports(transmission.FEATURE_JSON_RPC) {
+		server.Close()
+		t.Fatal("Client doesn't see JSON-RPC support")
+	}
+	return server, client
+}
+
+func TestJSONRPCTorrentGetTable(t *testing.T) {
+	server, client := jsonrpcServer(t)
+	defer server.Close()
+	id := server.Daemon.Add(jsonrpcTorrent())
+
+	calls := server.Daemon.JSONRPCCalls()
+	torrents, err := client.TorrentGet([]int{ id }, jsonrpcFields...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if server.Daemon.JSONRPCCalls() != calls + 1 {
+		t.Fatal("Request wasn't made with JSON-RPC")
+	}
+	if len(torrents) != 1 {
+		t.Fatalf("Expected 1 torrent, got %d", len(torrents))
+	}
+	checkJSONRPCTorrent(t, torrents[0])
+}
+
+func TestJSONRPCTorrentGetObjects(t *testing.T) {
+	server := NewServer(nil)
+	defer server.Close()
+	id := server.Daemon.Add(jsonrpcTorrent())
+
+	// Capabilities of a daemon without the table format, so torrents come as
+	// objects, then the same daemon speaking JSON-RPC.
+	server.Daemon.SetSession("rpc-version", 15)
+	client := server.Client()
+	if _, err := client.Capabilities(); err != nil {
+		t.Fatal(err)
+	}
+	server.Daemon.SetSession("rpc-version", transmission.FEATURE_JSON_RPC.Version())
+	client.Transport = &transmission.JSONRPCTransport{}
+
+	torrents, err := client.TorrentGet([]int{ id }, jsonrpcFields...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if server.Daemon.JSONRPCCalls() != 1 {
+		t.Fatal("Request wasn't made with JSON-RPC")
+	}
+	if len(torrents) != 1 {
+		t.Fatalf("Expected 1 torrent, got %d", len(torrents))
+	}
+	checkJSONRPCTorrent(t, torrents[0])
+}
+
+func TestJSONRPCTorrentAdd(t *testing.T) {
+	server, client := jsonrpcServer(t)
+	defer server.Close()
+
+	options := transmission.AddOptions{
+		DownloadDir: "/data",
+		Paused: true,
+		FilesUnwanted: []int{ 0 },
+		PeerLimit: 30,
+	}
+	result, err := client.AddTorrentWithOptions("/tmp/example.torrent", options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Duplicate || result.Torrent.Id == 0 || result.Torrent.Name != "example" || result.Torrent.HashString == "" {
+		t.Fatalf("Unexpected add result: %+v", result)
+	}
+
+	torrents, err := client.TorrentGet([]int{ result.Torrent.Id }, "id", "status", "downloadDir", "peer-limit", "fileStats")
+	if err != nil {
+		t.Fatal(err)
+	}
+	torrent := torrents[0]
+	if torrent.Status != transmission.TR_STATUS_STOPPED || torrent.DownloadDir != "/data" || torrent.PeerLimit != 30 {
+		t.Errorf("Options weren't applied: %+v", torrent)
+	}
+	if len(torrent.FileStats) == 0 || torrent.FileStats[0].Wanted {
+		t.Errorf("First file should be unwanted: %+v", torrent.FileStats)
+	}
+
+	duplicate, err := client.AddTorrentWithOptions("/tmp/example.torrent", options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !duplicate.Duplicate || duplicate.Torrent.Id != result.Torrent.Id {
+		t.Errorf("Expected a duplicate of %d, got %+v", result.Torrent.Id, duplicate)
+	}
+}
+
+func TestJSONRPCSessionGet(t *testing.T) {
+	server, client := jsonrpcServer(t)
+	defer server.Close()
+	server.Daemon.SetSession("speed-limit-up", 250)
+	server.Daemon.SetSession("speed-limit-down-enabled", true)
+
+	calls := server.Daemon.JSONRPCCalls()
+	settings, err := client.GetSessionSettings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if server.Daemon.JSONRPCCalls() != calls + 1 {
+		t.Fatal("Request wasn't made with JSON-RPC")
+	}
+
+	expected := &transmission.SessionSettings{
+		UploadSpeedLimit: 250,
+		UploadSpeedLimitEnabled: false,
+		DownloadSpeedLimit: 100,
+		DownloadSpeedLimitEnabled: true,
+	}
+	if !reflect.DeepEqual(settings, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, settings)
+	}
+}
+
+// Formats have to agree on every field, not just the ones checked above.
+func TestJSONRPCFormatsMatch(t *testing.T) {
+	server, client := jsonrpcServer(t)
+	defer server.Close()
+	id := server.Daemon.Add(jsonrpcTorrent())
+
+	table, err := client.TorrentGet([]int{ id }, jsonrpcFields...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	legacy := server.Client()
+	legacy.Transport = transmission.LegacyTransport{}
+	objects, err := legacy.TorrentGet([]int{ id }, jsonrpcFields...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if fmt.Sprintf("%+v", table) != fmt.Sprintf("%+v", objects) {
+		t.Errorf("JSON-RPC and legacy results differ:\n%+v\n%+v", table, objects)
+	}
+}
@@ -0,0 +1,508 @@
+package transmissiontest
+
+import (
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"math/rand"
+	"net/url"
+	"path"
+	"strings"
+	"transmission"
+	"metainfo"
+)
+
+type method func(daemon *Daemon, args map[string]interface{}) (map[string]interface{}, string)
+
+var methods = map[string]method{
+	"torrent-get": torrentGet,
+	"torrent-add": torrentAdd,
+	"torrent-remove": torrentRemove,
+	"torrent-set": torrentSet,
+	"torrent-set-location": torrentSetLocation,
+	"torrent-start": torrentStart,
+	"torrent-start-now": torrentStart,
+	"torrent-stop": torrentStop,
+	"torrent-verify": torrentVerify,
+	"session-get": sessionGet,
+	"session-set": sessionSet,
+	"session-close": sessionClose,
+}
+
+func (daemon *Daemon) dispatch(name string, args map[string]interface{}) (map[string]interface{}, string) {
+	handler, ok := methods[name]
+	if !ok {
+		return nil, "method name not recognized"
+	}
+	return handler(daemon, args)
+}
+
+/* Torrents */
+
+func torrentGet(daemon *Daemon, args map[string]interface{}) (map[string]interface{}, string) {
+	fields := stringList(args["fields"])
+	if len(fields) == 0 {
+		return nil, "no fields specified"
+	}
+
+	torrents, recent := daemon.selectTorrents(args["ids"])
+
+	var output map[string]interface{}
+	if args["format"] == "table" && toInt(daemon.session["rpc-version"]) >= 16 {
+		output = map[string]interface{}{ "torrents": torrentTable(torrents, fields) }
+	} else {
+		objects := make([]map[string]interface{}, len(torrents))
+		for index, torrent := range torrents {
+			objects[index] = torrentObject(torrent, fields)
+		}
+		output = map[string]interface{}{ "torrents": objects }
+	}
+
+	if recent {
+		output["removed"] = daemon.recentlyRemoved()
+	}
+	return output, "success"
+}
+
+func torrentAdd(daemon *Daemon, args map[string]interface{}) (map[string]interface{}, string) {
+	var torrent Torrent
+
+	if encoded, ok := args["metainfo"].(string); ok {
+		data, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, "invalid or corrupt torrent file"
+		}
+
+		// Real torrent files keep their name and files, anything else gets
+		// generated ones.
+		if info, err := metainfo.Parse(data); err == nil {
+			torrent.HashString = info.InfoHashString()
+			torrent.Name = info.Name
+			for _, file := range info.Files {
+				torrent.Files = append(torrent.Files, File{ Name: file.Path, Length: file.Length, Wanted: true })
+			}
+			torrent.setTrackers(info.Trackers)
+		} else {
+			sum := sha1.Sum(data)
+			torrent.HashString = hex.EncodeToString(sum[:])
+			torrent.Name = fmt.Sprintf("torrent-%s", torrent.HashString[:8])
+			torrent.Files = generateFiles(torrent.Name, torrent.HashString)
+		}
+		torrent.MetadataPercentComplete = 1
+	} else if filename, ok := args["filename"].(string); ok && filename != "" {
+		if strings.HasPrefix(filename, "magnet:") {
+			magnet, err := url.Parse(filename)
+			if err != nil {
+				return nil, "invalid or corrupt torrent file"
+			}
+
+			query := magnet.Query()
+			xt := query.Get("xt")
+			if !strings.HasPrefix(xt, "urn:btih:") {
+				return nil, "invalid or corrupt torrent file"
+			}
+
+			torrent.HashString = strings.ToLower(strings.TrimPrefix(xt, "urn:btih:"))
+			torrent.Name = query.Get("dn")
+			if torrent.Name == "" {
+				torrent.Name = torrent.HashString
+			}
+			torrent.pending = generateFiles(torrent.Name, torrent.HashString)
+
+			tiers := [][]string{}
+			for _, tracker := range query["tr"] {
+				tiers = append(tiers, []string{ tracker })
+			}
+			torrent.setTrackers(tiers)
+		} else {
+			torrent.Name = strings.TrimSuffix(path.Base(filename), ".torrent")
+			torrent.HashString = hashOf(filename)
+			torrent.Files = generateFiles(torrent.Name, torrent.HashString)
+			torrent.MetadataPercentComplete = 1
+		}
+	} else {
+		return nil, "no filename or metainfo specified"
+	}
+
+	if existing := daemon.findHash(torrent.HashString); existing != nil {
+		return map[string]interface{}{
+			"torrent-duplicate": addedInfo(existing),
+		}, "success"
+	}
+
+	torrent.Status = transmission.TR_STATUS_DOWNLOAD
+	if paused, _ := args["paused"].(bool); paused {
+		torrent.Status = transmission.TR_STATUS_STOPPED
+	}
+
+	if dir, ok := args["download-dir"].(string); ok && dir != "" {
+		torrent.DownloadDir = dir
+	}
+
+	// Like the real daemon, new torrents get the session's per-torrent limit.
+	torrent.PeerLimit = toInt(daemon.session["peer-limit-per-torrent"])
+
+	seed := seededRand(torrent.HashString)
+	torrent.DownloadSpeed = int64(seed.Intn(4 * 1024 * 1024))
+	torrent.UploadSpeed = int64(seed.Intn(512 * 1024))
+	torrent.PeersConnected = seed.Intn(40)
+
+	applySettings(&torrent, args)
+	id := daemon.add(torrent)
+
+	return map[string]interface{}{
+		"torrent-added": addedInfo(daemon.find(id)),
+	}, "success"
+}
+
+func torrentRemove(daemon *Daemon, args map[string]interface{}) (map[string]interface{}, string) {
+	torrents, _ := daemon.selectTorrents(args["ids"])
+	for _, torrent := range torrents {
+		daemon.remove(torrent.Id)
+	}
+	return nil, "success"
+}
+
+func torrentSet(daemon *Daemon, args map[string]interface{}) (map[string]interface{}, string) {
+	torrents, _ := daemon.selectTorrents(args["ids"])
+	now := daemon.now().Unix()
+
+	for _, torrent := range torrents {
+		applySettings(torrent, args)
+		applyTrackers(torrent, args)
+
+		if value, ok := args["downloadLimit"]; ok {
+			torrent.DownloadLimit = toInt(value)
+		}
+		if value, ok := args["downloadLimited"].(bool); ok {
+			torrent.DownloadLimited = value
+		}
+		if value, ok := args["uploadLimit"]; ok {
+			torrent.UploadLimit = toInt(value)
+		}
+		if value, ok := args["uploadLimited"].(bool); ok {
+			torrent.UploadLimited = value
+		}
+
+		// Completed torrent may start downloading again after new files were selected.
+		if torrent.Status == transmission.TR_STATUS_SEED && torrent.leftUntilDone() > 0 {
+			torrent.Status = transmission.TR_STATUS_DOWNLOAD
+		}
+		torrent.ActivityDate = now
+	}
+
+	return nil, "success"
+}
+
+func torrentSetLocation(daemon *Daemon, args map[string]interface{}) (map[string]interface{}, string) {
+	location, ok := args["location"].(string)
+	if !ok || location == "" {
+		return nil, "no location specified"
+	}
+
+	torrents, _ := daemon.selectTorrents(args["ids"])
+	for _, torrent := range torrents {
+		torrent.DownloadDir = location
+		torrent.ActivityDate = daemon.now().Unix()
+	}
+
+	return nil, "success"
+}
+
+func torrentStart(daemon *Daemon, args map[string]interface{}) (map[string]interface{}, string) {
+	torrents, _ := daemon.selectTorrents(args["ids"])
+	for _, torrent := range torrents {
+		if torrent.leftUntilDone() == 0 && torrent.MetadataPercentComplete >= 1 {
+			torrent.Status = transmission.TR_STATUS_SEED
+		} else {
+			torrent.Status = transmission.TR_STATUS_DOWNLOAD
+		}
+		torrent.ActivityDate = daemon.now().Unix()
+	}
+	return nil, "success"
+}
+
+func torrentStop(daemon *Daemon, args map[string]interface{}) (map[string]interface{}, string) {
+	torrents, _ := daemon.selectTorrents(args["ids"])
+	for _, torrent := range torrents {
+		torrent.Status = transmission.TR_STATUS_STOPPED
+		torrent.ActivityDate = daemon.now().Unix()
+	}
+	return nil, "success"
+}
+
+func torrentVerify(daemon *Daemon, args map[string]interface{}) (map[string]interface{}, string) {
+	return nil, "success"
+}
+
+/* Session */
+
+func sessionGet(daemon *Daemon, args map[string]interface{}) (map[string]interface{}, string) {
+	fields := stringList(args["fields"])
+
+	output := make(map[string]interface{})
+	for key, value := range daemon.session {
+		if len(fields) == 0 || contains(fields, key) {
+			output[key] = value
+		}
+	}
+	if len(fields) == 0 || contains(fields, "session-id") {
+		output["session-id"] = daemon.sessionId
+	}
+
+	return output, "success"
+}
+
+func sessionSet(daemon *Daemon, args map[string]interface{}) (map[string]interface{}, string) {
+	for key, value := range args {
+		switch key {
+		case "version", "rpc-version", "rpc-version-minimum", "rpc-version-semver", "session-id":
+			// Read-only.
+		default:
+			daemon.session[key] = value
+		}
+	}
+	return nil, "success"
+}
+
+func sessionClose(daemon *Daemon, args map[string]interface{}) (map[string]interface{}, string) {
+	daemon.closed = true
+	return nil, "success"
+}
+
+/* Argument helpers */
+
+// Resolves 'ids' argument. Second value is true for 'recently-active' requests.
+func (daemon *Daemon) selectTorrents(ids interface{}) ([]*Torrent, bool) {
+	switch value := ids.(type) {
+	case nil:
+		return daemon.torrents, false
+	case float64:
+		if torrent := daemon.find(int(value)); torrent != nil {
+			return []*Torrent{torrent}, false
+		}
+	case string:
+		if value == "recently-active" {
+			return daemon.recentlyActive(), true
+		}
+		if torrent := daemon.findHash(value); torrent != nil {
+			return []*Torrent{torrent}, false
+		}
+	case []interface{}:
+		output := make([]*Torrent, 0, len(value))
+		for _, item := range value {
+			var torrent *Torrent
+			switch id := item.(type) {
+			case float64:
+				torrent = daemon.find(int(id))
+			case string:
+				torrent = daemon.findHash(id)
+			}
+			if torrent != nil {
+				output = append(output, torrent)
+			}
+		}
+		return output, false
+	}
+
+	return []*Torrent{}, false
+}
+
+func (daemon *Daemon) recentlyActive() []*Torrent {
+	threshold := daemon.now().Unix() - RECENTLY_ACTIVE_SECONDS
+
+	output := make([]*Torrent, 0)
+	for _, torrent := range daemon.torrents {
+		if torrent.ActivityDate >= threshold || torrent.AddedDate >= threshold {
+			output = append(output, torrent)
+		}
+	}
+	return output
+}
+
+func (daemon *Daemon) recentlyRemoved() []int {
+	threshold := daemon.now().Unix() - RECENTLY_ACTIVE_SECONDS
+
+	output := make([]int, 0)
+	for _, removed := range daemon.removed {
+		if removed.date >= threshold {
+			output = append(output, removed.id)
+		}
+	}
+	return output
+}
+
+// Settings shared by 'torrent-add' and 'torrent-set'.
+func applySettings(torrent *Torrent, args map[string]interface{}) {
+	// Magnets without metadata keep file settings until the files are known.
+	files := torrent.Files
+	if torrent.MetadataPercentComplete < 1 {
+		files = torrent.pending
+	}
+
+	setFiles := func(key string, apply func(*File)) {
+		indices := intList(args[key])
+		// Empty list means 'all files'.
+		if value, present := args[key]; present && len(indices) == 0 && value != nil {
+			for index := range files {
+				apply(&files[index])
+			}
+			return
+		}
+		for _, index := range indices {
+			if index >= 0 && index < len(files) {
+				apply(&files[index])
+			}
+		}
+	}
+
+	setFiles("files-wanted", func(file *File) { file.Wanted = true })
+	setFiles("files-unwanted", func(file *File) { file.Wanted = false })
+	setFiles("priority-high", func(file *File) { file.Priority = transmission.TR_PRIORITY_HIGH })
+	setFiles("priority-normal", func(file *File) { file.Priority = transmission.TR_PRIORITY_NORMAL })
+	setFiles("priority-low", func(file *File) { file.Priority = transmission.TR_PRIORITY_LOW })
+
+	if value, ok := args["bandwidthPriority"]; ok {
+		torrent.BandwidthPriority = toInt(value)
+	}
+	if value, ok := args["peer-limit"]; ok {
+		torrent.PeerLimit = toInt(value)
+	}
+	if _, ok := args["labels"]; ok {
+		torrent.Labels = stringList(args["labels"])
+	}
+}
+
+// Tracker edits of 'torrent-set'. Legacy arguments are applied in the order
+// the daemon does it: add, remove, replace.
+func applyTrackers(torrent *Torrent, args map[string]interface{}) {
+	if list, ok := args["trackerList"].(string); ok {
+		torrent.setTrackers(transmission.ParseTrackerList(list))
+		return
+	}
+
+	tiers := torrent.trackerTiers()
+	for _, url := range stringList(args["trackerAdd"]) {
+		if torrent.findTracker(url) < 0 {
+			tiers = append(tiers, []string{ url })
+		}
+	}
+	torrent.setTrackers(tiers)
+
+	removed := intList(args["trackerRemove"])
+	trackers := []Tracker{}
+	for _, tracker := range torrent.Trackers {
+		if !containsInt(removed, tracker.Id) {
+			trackers = append(trackers, tracker)
+		}
+	}
+	torrent.Trackers = trackers
+
+	// Pairs of tracker id and new URL.
+	replace, _ := args["trackerReplace"].([]interface{})
+	for index := 0; index + 1 < len(replace); index += 2 {
+		id, _ := replace[index].(float64)
+		url, _ := replace[index + 1].(string)
+		for position := range torrent.Trackers {
+			if torrent.Trackers[position].Id == int(id) && url != "" {
+				torrent.Trackers[position].Announce = url
+			}
+		}
+	}
+}
+
+func addedInfo(torrent *Torrent) map[string]interface{} {
+	return map[string]interface{}{
+		"id": torrent.Id,
+		"name": torrent.Name,
+		"hashString": torrent.HashString,
+	}
+}
+
+func toInt(value interface{}) int {
+	switch number := value.(type) {
+	case float64:
+		return int(number)
+	case int:
+		return number
+	}
+	return 0
+}
+
+func intList(value interface{}) []int {
+	list, _ := value.([]interface{})
+	output := make([]int, 0, len(list))
+	for _, item := range list {
+		if number, ok := item.(float64); ok {
+			output = append(output, int(number))
+		}
+	}
+	return output
+}
+
+func stringList(value interface{}) []string {
+	list, _ := value.([]interface{})
+	output := make([]string, 0, len(list))
+	for _, item := range list {
+		if str, ok := item.(string); ok {
+			output = append(output, str)
+		}
+	}
+	return output
+}
+
+func containsInt(list []int, value int) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+/* Generators */
+
+func hashOf(value string) string {
+	sum := sha1.Sum([]byte(value))
+	return hex.EncodeToString(sum[:])
+}
+
+// Deterministic source, so the same torrent always gets the same content.
+func seededRand(hash string) *rand.Rand {
+	var seed int64
+	for _, symbol := range hash {
+		seed = seed * 31 + int64(symbol)
+	}
+	return rand.New(rand.NewSource(seed))
+}
+
+func generateFiles(name string, hash string) []File {
+	random := seededRand(hash)
+	count := 1 + random.Intn(5)
+
+	if count == 1 {
+		return []File{
+			File{ Name: name, Length: int64(1 + random.Intn(2048)) * 1024 * 1024, Wanted: true },
+		}
+	}
+
+	files := make([]File, count)
+	for index := range files {
+		files[index] = File{
+			Name: fmt.Sprintf("%s/part%02d.bin", name, index + 1),
+			Length: int64(1 + random.Intn(512)) * 1024 * 1024,
+			Wanted: true,
+		}
+	}
+	return files
+}
@@ -0,0 +1,47 @@
+package transmissiontest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"transmission"
+)
+
+// Fake daemon served over HTTP on a local port.
+type Server struct {
+	*httptest.Server
+	Daemon *Daemon
+}
+
+// Starts serving the daemon's RPC endpoint. Nil daemon means a new empty one.
+// Call Close() when done.
+func NewServer(daemon *Daemon) *Server {
+	if daemon == nil {
+		daemon = NewDaemon()
+	}
+
+	rpcPath := strings.TrimSuffix(transmission.DEFAULT_RPC_PATH, "/")
+	mux := http.NewServeMux()
+	mux.Handle(rpcPath, daemon)
+	mux.Handle(rpcPath + "/", daemon)
+
+	return &Server{ httptest.NewServer(mux), daemon }
+}
+
+// Connection spec pointing to the server.
+func (server *Server) Connection() transmission.Connection {
+	connection, err := transmission.ParseConnection(server.URL)
+	if err != nil {
+		panic(err)
+	}
+	return connection
+}
+
+// New client connected to the server.
+func (server *Server) Client() *transmission.Client {
+	client, err := transmission.NewClient(server.Connection())
+	if err != nil {
+		panic(err)
+	}
+	return client
+}
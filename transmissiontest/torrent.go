@@ -0,0 +1,300 @@
+package transmissiontest
+
+import (
+	"net/url"
+	"strings"
+	"transmission"
+)
+
+/* Data */
+
+type File struct {
+	Name string
+	Length int64
+	BytesCompleted int64
+	Wanted bool
+	Priority int
+}
+
+// Discovery sources, as reported in 'peersFrom'.
+const (
+	PEER_FROM_TRACKER = "tracker"
+	PEER_FROM_DHT = "dht"
+	PEER_FROM_PEX = "pex"
+	PEER_FROM_LPD = "lpd"
+	PEER_FROM_INCOMING = "incoming"
+	PEER_FROM_CACHE = "cache"
+	PEER_FROM_LTEP = "ltep"
+)
+
+type Peer struct {
+	Address string
+	Port int
+	ClientName string
+	Progress float64
+	// Bytes per second.
+	RateToClient int64
+	RateToPeer int64
+	IsEncrypted bool
+	IsUTP bool
+	IsIncoming bool
+	Source string
+}
+
+// Seconds between announces.
+const ANNOUNCE_INTERVAL = 1800
+
+type Tracker struct {
+	Id int
+	Announce string
+	Tier int
+	// Unix timestamp, zero if it never announced.
+	LastAnnounce int64
+	// Messages of the failed announce or scrape, empty if they succeeded.
+	AnnounceError string
+	ScrapeError string
+	// Swarm size from the last scrape.
+	Seeders int
+	Leechers int
+}
+
+// In-memory torrent. Speeds are nominal values used while the torrent
+// is active; reported rates drop to zero when it's stopped or done.
+type Torrent struct {
+	Id int
+	HashString string
+	Name string
+	DownloadDir string
+	Status int
+	Files []File
+	Comment string
+	Creator string
+	IsPrivate bool
+	Labels []string
+	BandwidthPriority int
+	PeerLimit int
+	Error int
+	ErrorString string
+
+	// Nominal transfer speeds, bytes per second.
+	DownloadSpeed int64
+	UploadSpeed int64
+
+	DownloadLimit int
+	DownloadLimited bool
+	UploadLimit int
+	UploadLimited bool
+
+	UploadedEver int64
+	DownloadedEver int64
+	// Used when there's no detailed peer list.
+	PeersConnected int
+	Peers []Peer
+	Trackers []Tracker
+
+	// Magnet links start without metadata; files appear once it reaches 1.
+	MetadataPercentComplete float64
+	pending []File
+
+	// Unix timestamps.
+	AddedDate int64
+	DoneDate int64
+	ActivityDate int64
+
+	// Base speeds for demo fluctuations.
+	baseDownload int64
+	baseUpload int64
+}
+
+/* Helpers */
+
+func (torrent *Torrent) totalSize() int64 {
+	var total int64
+	for _, file := range torrent.Files {
+		total += file.Length
+	}
+	return total
+}
+
+func (torrent *Torrent) sizeWhenDone() int64 {
+	var total int64
+	for _, file := range torrent.Files {
+		if file.Wanted {
+			total += file.Length
+		}
+	}
+	return total
+}
+
+func (torrent *Torrent) leftUntilDone() int64 {
+	var left int64
+	for _, file := range torrent.Files {
+		if file.Wanted {
+			left += file.Length - file.BytesCompleted
+		}
+	}
+	return left
+}
+
+func (torrent *Torrent) haveValid() int64 {
+	var have int64
+	for _, file := range torrent.Files {
+		have += file.BytesCompleted
+	}
+	return have
+}
+
+func (torrent *Torrent) isActive() bool {
+	return torrent.Status == transmission.TR_STATUS_DOWNLOAD ||
+		torrent.Status == transmission.TR_STATUS_SEED
+}
+
+func (torrent *Torrent) rateDownload() int64 {
+	if torrent.Status != transmission.TR_STATUS_DOWNLOAD || torrent.MetadataPercentComplete < 1 {
+		return 0
+	}
+	return limitRate(torrent.DownloadSpeed, torrent.DownloadLimit, torrent.DownloadLimited)
+}
+
+func (torrent *Torrent) rateUpload() int64 {
+	// Nothing to share yet.
+	if !torrent.isActive() || torrent.haveValid() == 0 {
+		return 0
+	}
+	return limitRate(torrent.UploadSpeed, torrent.UploadLimit, torrent.UploadLimited)
+}
+
+func (torrent *Torrent) percentDone() float64 {
+	size := torrent.sizeWhenDone()
+	if size == 0 {
+		return 0
+	}
+	return float64(size - torrent.leftUntilDone()) / float64(size)
+}
+
+func (torrent *Torrent) eta() int64 {
+	if torrent.leftUntilDone() == 0 {
+		return transmission.TR_ETA_NOT_AVAIL
+	}
+
+	rate := torrent.rateDownload()
+	if rate == 0 {
+		return transmission.TR_ETA_UNKNOWN
+	}
+	return torrent.leftUntilDone() / rate
+}
+
+func (torrent *Torrent) uploadRatio() float64 {
+	if torrent.DownloadedEver == 0 {
+		return -1
+	}
+	return float64(torrent.UploadedEver) / float64(torrent.DownloadedEver)
+}
+
+func (torrent *Torrent) peersConnected() int {
+	if !torrent.isActive() {
+		return 0
+	}
+	if len(torrent.Peers) > 0 {
+		return len(torrent.Peers)
+	}
+	return torrent.PeersConnected
+}
+
+func (torrent *Torrent) activePeers() []Peer {
+	if !torrent.isActive() {
+		return []Peer{}
+	}
+	return torrent.Peers
+}
+
+// Limits are in KB/s, same as in the RPC.
+func limitRate(speed int64, limit int, limited bool) int64 {
+	if limited && int64(limit) * 1024 < speed {
+		return int64(limit) * 1024
+	}
+	return speed
+}
+
+func (torrent *Torrent) copy() Torrent {
+	output := *torrent
+	output.Files = append([]File{}, torrent.Files...)
+	output.pending = append([]File{}, torrent.pending...)
+	output.Labels = append([]string{}, torrent.Labels...)
+	output.Peers = append([]Peer{}, torrent.Peers...)
+	output.Trackers = append([]Tracker{}, torrent.Trackers...)
+	return output
+}
+
+/* Trackers */
+
+// Announce URLs grouped by tier.
+func (torrent *Torrent) trackerTiers() [][]string {
+	trackers := make([]transmission.Tracker, len(torrent.Trackers))
+	for index, tracker := range torrent.Trackers {
+		trackers[index] = transmission.Tracker{ Announce: tracker.Announce, Tier: tracker.Tier }
+	}
+	return transmission.TrackerTiers(trackers)
+}
+
+// Replaces the tracker list. Trackers that stay keep their ids and stats.
+func (torrent *Torrent) setTrackers(tiers [][]string) {
+	next := torrent.nextTrackerId()
+
+	trackers := []Tracker{}
+	for tier, urls := range tiers {
+		for _, url := range urls {
+			tracker := Tracker{ Id: next, Announce: url, Seeders: -1, Leechers: -1 }
+			if index := torrent.findTracker(url); index >= 0 {
+				tracker = torrent.Trackers[index]
+			} else {
+				next += 1
+			}
+			tracker.Tier = tier
+			trackers = append(trackers, tracker)
+		}
+	}
+	torrent.Trackers = trackers
+}
+
+func (torrent *Torrent) findTracker(url string) int {
+	for index, tracker := range torrent.Trackers {
+		if tracker.Announce == url {
+			return index
+		}
+	}
+	return -1
+}
+
+func (torrent *Torrent) nextTrackerId() int {
+	id := 0
+	for _, tracker := range torrent.Trackers {
+		if tracker.Id >= id {
+			id = tracker.Id + 1
+		}
+	}
+	return id
+}
+
+// Host of the announce URL, the way 'trackerStats' reports it.
+func trackerHost(announce string) string {
+	parsed, err := url.Parse(announce)
+	if err != nil {
+		return announce
+	}
+	return parsed.Scheme + "://" + parsed.Host
+}
+
+// Second-level domain, like 'example' for 'tracker.example.org'.
+func trackerSitename(announce string) string {
+	parsed, err := url.Parse(announce)
+	if err != nil {
+		return ""
+	}
+
+	parts := strings.Split(parsed.Hostname(), ".")
+	if len(parts) < 2 {
+		return parsed.Hostname()
+	}
+	return parts[len(parts) - 2]
+}
@@ -16,6 +16,7 @@ type Attribute string
 
 const(
 	ATTR_BOLD Attribute = "1"
+	ATTR_DIM						= "2"
 	ATTR_REVERSED				= "7"
 	ATTR_NORMAL					= "27"
 )
@@ -1,13 +1,29 @@
 package utils
 
 import (
+	"hash/fnv"
 	"math/rand"
+	"unicode"
 )
 
-func RandomString(length int) string {
-	runes := make([]rune, length)
-	for i := 0; i < length; i++ {
-		runes[i] = rune(rand.Int() % 94 + 32)
+// Replaces letters and digits with random ones, keeping case, separators and
+// length intact. The result depends only on the input, so the same name looks
+// the same on every redraw.
+func Obfuscate(input string) string {
+	hash := fnv.New64a()
+	hash.Write([]byte(input))
+	random := rand.New(rand.NewSource(int64(hash.Sum64())))
+
+	runes := []rune(input)
+	for i, r := range runes {
+		switch {
+		case unicode.IsUpper(r):
+			runes[i] = rune('A' + random.Intn(26))
+		case unicode.IsLetter(r):
+			runes[i] = rune('a' + random.Intn(26))
+		case unicode.IsDigit(r):
+			runes[i] = rune('0' + random.Intn(10))
+		}
 	}
 	return string(runes)
 }
@@ -0,0 +1,8 @@
+module verify
+
+go 1.13
+
+require (
+    creator v0.0.0
+    metainfo v0.0.0
+)
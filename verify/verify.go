@@ -0,0 +1,350 @@
+package verify
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"errors"
+	"fmt"
+	"io"
+	"metainfo"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+/* Report */
+
+const (
+	STATUS_OK = "ok"
+	// File doesn't exist, or a piece overlaps one that doesn't.
+	STATUS_MISSING = "missing"
+	// File is smaller than the torrent says, or a piece overlaps one that is.
+	STATUS_SHORT = "short"
+	// Data is there, but hashes don't match.
+	STATUS_CORRUPT = "corrupt"
+	// File's own data is there, but pieces it shares with broken neighbours
+	// can't be checked.
+	STATUS_UNVERIFIED = "unverified"
+)
+
+type FileReport struct {
+	// Path as listed in the torrent, relative to the data directory.
+	Path string		`json:"path"`
+	Length int64	`json:"length"`
+	// Size on disk, -1 if the file doesn't exist.
+	Size int64		`json:"size"`
+	Status string `json:"status"`
+	// Pieces overlapping the file that didn't check out.
+	BadPieces int `json:"badPieces"`
+}
+
+type Report struct {
+	Name string				 `json:"name"`
+	InfoHash string		 `json:"infoHash"`
+	Directory string	 `json:"directory"`
+	PieceCount int		 `json:"pieceCount"`
+	GoodPieces int		 `json:"goodPieces"`
+	Complete bool			 `json:"complete"`
+	Missing []int			 `json:"missingPieces"`
+	Short []int				 `json:"shortPieces"`
+	Corrupt []int			 `json:"corruptPieces"`
+	Files []FileReport `json:"files"`
+}
+
+// Files that aren't fully verified.
+func (report *Report) BadFiles() []FileReport {
+	files := []FileReport{}
+	for _, file := range report.Files {
+		if file.Status != STATUS_OK {
+			files = append(files, file)
+		}
+	}
+	return files
+}
+
+// Called from hashing goroutines with the number of bytes checked so far.
+type ProgressFunc func(checked int64, total int64)
+
+/* Verifying */
+
+type file struct {
+	// Path in the torrent, and on disk.
+	name string
+	path string
+	length int64
+	// Position of the file within the concatenated data.
+	offset int64
+	// Size on disk, -1 if missing.
+	size int64
+}
+
+// Checks data in the directory against the torrent's piece hashes. Files are
+// expected at the same paths the daemon would download them to. Workers set
+// the number of hashing goroutines, zero means one per CPU.
+func Verify(
+	ctx context.Context,
+	info *metainfo.MetaInfo,
+	directory string,
+	workers int,
+	progress ProgressFunc,
+) (*Report, error) {
+	total := info.TotalSize()
+	expected := int((total + info.PieceLength - 1) / info.PieceLength)
+	if expected != info.PieceCount {
+		return nil, fmt.Errorf("Torrent has %d piece hashes for %d pieces", info.PieceCount, expected)
+	}
+
+	files, err := locate(info, directory)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{
+		Name: info.Name,
+		InfoHash: info.InfoHashString(),
+		Directory: directory,
+		PieceCount: info.PieceCount,
+		Missing: []int{},
+		Short: []int{},
+		Corrupt: []int{},
+		Files: make([]FileReport, len(files)),
+	}
+
+	statuses, err := checkPieces(ctx, info, files, workers, progress)
+	if err != nil {
+		return nil, err
+	}
+
+	for index, status := range statuses {
+		switch status {
+		case STATUS_OK:
+			report.GoodPieces += 1
+		case STATUS_MISSING:
+			report.Missing = append(report.Missing, index)
+		case STATUS_SHORT:
+			report.Short = append(report.Short, index)
+		case STATUS_CORRUPT:
+			report.Corrupt = append(report.Corrupt, index)
+		}
+	}
+	report.Complete = report.GoodPieces == report.PieceCount
+
+	for index, file := range files {
+		report.Files[index] = fileReport(info, file, statuses)
+	}
+	return report, nil
+}
+
+// Paths and sizes of the torrent's files in the directory.
+func locate(info *metainfo.MetaInfo, directory string) ([]file, error) {
+	files := make([]file, len(info.Files))
+
+	var offset int64
+	for index, item := range info.Files {
+		// Torrents come from anywhere, don't let them point outside.
+		for _, component := range strings.Split(item.Path, "/") {
+			if component == "" || component == "." || component == ".." {
+				return nil, fmt.Errorf("Torrent has unsafe file path '%s'", item.Path)
+			}
+		}
+
+		path := filepath.Join(directory, filepath.FromSlash(item.Path))
+
+		size := int64(-1)
+		if stat, err := os.Stat(path); err == nil && stat.Mode().IsRegular() {
+			size = stat.Size()
+		} else if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+
+		files[index] = file{ item.Path, path, item.Length, offset, size }
+		offset += item.Length
+	}
+	return files, nil
+}
+
+// Status of every piece. Pieces overlapping missing or short files aren't
+// read at all.
+func checkPieces(
+	ctx context.Context,
+	info *metainfo.MetaInfo,
+	files []file,
+	workers int,
+	progress ProgressFunc,
+) ([]string, error) {
+	statuses := make([]string, info.PieceCount)
+	pending := []int{}
+	for index := range statuses {
+		statuses[index] = availability(info, files, index)
+		if statuses[index] == STATUS_OK {
+			pending = append(pending, index)
+		}
+	}
+
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	indexes := make(chan int)
+	go func() {
+		defer close(indexes)
+		for _, index := range pending {
+			select {
+			case indexes <- index:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	// Pieces that can't be read still count as checked.
+	total := info.TotalSize()
+	var checked int64
+	for index, status := range statuses {
+		if status != STATUS_OK {
+			checked += info.PieceSize(index)
+		}
+	}
+	if progress != nil {
+		progress(checked, total)
+	}
+
+	data := dataFiles(files)
+
+	var failure error
+	var failureOnce sync.Once
+	var group sync.WaitGroup
+
+	for worker := 0; worker < workers; worker++ {
+		group.Add(1)
+		go func() {
+			defer group.Done()
+
+			reader := metainfo.NewPieceReader(data)
+			defer reader.Close()
+
+			buffer := make([]byte, info.PieceLength)
+			for index := range indexes {
+				length := info.PieceSize(index)
+				err := reader.ReadRange(buffer[:length], int64(index) * info.PieceLength)
+
+				switch {
+				case errors.Is(err, io.ErrUnexpectedEOF):
+					// File was truncated after it was checked.
+					statuses[index] = STATUS_SHORT
+				case err != nil:
+					failureOnce.Do(func() { failure = err })
+					cancel()
+					return
+				default:
+					sum := sha1.Sum(buffer[:length])
+					if !bytes.Equal(sum[:], info.PieceHash(index)) {
+						statuses[index] = STATUS_CORRUPT
+					}
+				}
+
+				done := atomic.AddInt64(&checked, length)
+				if progress != nil {
+					progress(done, total)
+				}
+			}
+		}()
+	}
+
+	group.Wait()
+
+	if failure != nil {
+		return nil, failure
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return statuses, nil
+}
+
+// Whether all bytes of the piece are on disk: ok, missing or short.
+func availability(info *metainfo.MetaInfo, files []file, index int) string {
+	start := int64(index) * info.PieceLength
+	end := start + info.PieceSize(index)
+
+	status := STATUS_OK
+	for _, file := range overlapping(files, start, end) {
+		switch {
+		case file.size < 0:
+			return STATUS_MISSING
+		case file.size < file.length && file.size < end - file.offset:
+			status = STATUS_SHORT
+		}
+	}
+	return status
+}
+
+// Non-empty files with data between the offsets.
+func overlapping(files []file, start int64, end int64) []file {
+	first := sort.Search(len(files), func(index int) bool {
+		return files[index].offset + files[index].length > start
+	})
+
+	output := []file{}
+	for index := first; index < len(files) && files[index].offset < end; index++ {
+		if files[index].length > 0 {
+			output = append(output, files[index])
+		}
+	}
+	return output
+}
+
+func fileReport(info *metainfo.MetaInfo, file file, statuses []string) FileReport {
+	report := FileReport{
+		Path: file.name,
+		Length: file.length,
+		Size: file.size,
+		Status: STATUS_OK,
+	}
+
+	switch {
+	case file.size < 0:
+		report.Status = STATUS_MISSING
+	case file.size < file.length:
+		report.Status = STATUS_SHORT
+	}
+
+	if file.length == 0 {
+		return report
+	}
+
+	first := int(file.offset / info.PieceLength)
+	last := int((file.offset + file.length - 1) / info.PieceLength)
+	for index := first; index <= last; index++ {
+		if statuses[index] == STATUS_OK {
+			continue
+		}
+
+		report.BadPieces += 1
+		if report.Status == STATUS_OK || report.Status == STATUS_UNVERIFIED {
+			if statuses[index] == STATUS_CORRUPT {
+				report.Status = STATUS_CORRUPT
+			} else {
+				report.Status = STATUS_UNVERIFIED
+			}
+		}
+	}
+	return report
+}
+
+// Files for reading pieces from.
+func dataFiles(files []file) []metainfo.DataFile {
+	output := make([]metainfo.DataFile, len(files))
+	for index, file := range files {
+		output[index] = metainfo.DataFile{ Path: file.path, Length: file.length, Offset: file.offset }
+	}
+	return output
+}
@@ -0,0 +1,215 @@
+package verify
+
+import (
+	"context"
+	"creator"
+	"io/ioutil"
+	"metainfo"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// Four pieces of 16KiB: 'a' ends inside piece 1, 'c' spans pieces 1 to 3 and
+// shares the last one with 'd'. 'b' is empty and sits between them.
+var testFiles = []struct {
+	name string
+	length int
+}{
+	{ "a", 20000 },
+	{ "b", 0 },
+	{ "c", 30000 },
+	{ "d", 10000 },
+}
+
+// Creates the data and its torrent in a temporary directory. Data is in
+// 'data' under the returned directory.
+func setup(t *testing.T) (string, *metainfo.MetaInfo) {
+	directory, err := ioutil.TempDir("", "verify")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := filepath.Join(directory, "data")
+	if err := os.Mkdir(data, 0755); err != nil {
+		os.RemoveAll(directory)
+		t.Fatal(err)
+	}
+
+	for index, file := range testFiles {
+		contents := make([]byte, file.length)
+		for position := range contents {
+			contents[position] = byte(position * (index + 1))
+		}
+		if err := ioutil.WriteFile(filepath.Join(data, file.name), contents, 0644); err != nil {
+			os.RemoveAll(directory)
+			t.Fatal(err)
+		}
+	}
+
+	output := filepath.Join(directory, "data.torrent")
+	options := creator.Options{ Path: data, PieceLength: creator.MIN_PIECE_LENGTH }
+	if err := creator.CreateFile(context.Background(), options, output, false, nil); err != nil {
+		os.RemoveAll(directory)
+		t.Fatal(err)
+	}
+
+	info, err := metainfo.Load(output)
+	if err != nil {
+		os.RemoveAll(directory)
+		t.Fatal(err)
+	}
+	if info.PieceCount != 4 {
+		os.RemoveAll(directory)
+		t.Fatalf("Expected 4 pieces, got %d", info.PieceCount)
+	}
+	return directory, info
+}
+
+func verify(t *testing.T, directory string, info *metainfo.MetaInfo) *Report {
+	report, err := Verify(context.Background(), info, directory, 2, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return report
+}
+
+// Compares piece lists and file statuses, in 'a', 'b', 'c', 'd' order.
+func check(t *testing.T, report *Report, missing, short, corrupt []int, statuses ...string) {
+	pieces := [][]int{ report.Missing, report.Short, report.Corrupt }
+	expected := [][]int{ missing, short, corrupt }
+	if !reflect.DeepEqual(pieces, expected) {
+		t.Errorf("Expected missing, short and corrupt pieces %v, got %v", expected, pieces)
+	}
+
+	good := report.PieceCount - len(missing) - len(short) - len(corrupt)
+	if report.GoodPieces != good || report.Complete != (good == report.PieceCount) {
+		t.Errorf("Expected %d good pieces, got %d (complete: %v)", good, report.GoodPieces, report.Complete)
+	}
+
+	actual := make([]string, len(report.Files))
+	for index, file := range report.Files {
+		actual[index] = file.Status
+	}
+	if !reflect.DeepEqual(actual, statuses) {
+		t.Errorf("Expected file statuses %v, got %v", statuses, actual)
+	}
+}
+
+func TestIntact(t *testing.T) {
+	directory, info := setup(t)
+	defer os.RemoveAll(directory)
+
+	report := verify(t, directory, info)
+	check(t, report, []int{}, []int{}, []int{}, STATUS_OK, STATUS_OK, STATUS_OK, STATUS_OK)
+	if files := report.BadFiles(); len(files) != 0 {
+		t.Errorf("Expected no bad files, got %v", files)
+	}
+}
+
+func TestMissingFile(t *testing.T) {
+	directory, info := setup(t)
+	defer os.RemoveAll(directory)
+
+	if err := os.Remove(filepath.Join(directory, "data", "d")); err != nil {
+		t.Fatal(err)
+	}
+
+	// Last piece can't be read, so 'c' can't be fully checked either.
+	report := verify(t, directory, info)
+	check(t, report, []int{ 3 }, []int{}, []int{}, STATUS_OK, STATUS_OK, STATUS_UNVERIFIED, STATUS_MISSING)
+	if file := report.Files[3]; file.Size != -1 || file.BadPieces != 1 {
+		t.Errorf("Unexpected report for missing file: %+v", file)
+	}
+	if file := report.Files[2]; file.BadPieces != 1 {
+		t.Errorf("Expected 1 bad piece in 'c', got %d", file.BadPieces)
+	}
+}
+
+func TestShortFile(t *testing.T) {
+	cases := []struct {
+		description string
+		size int64
+		short []int
+	}{
+		// Piece 0 is incomplete, and so is piece 1 shared with 'c'.
+		{ "before piece boundary", 10000, []int{ 0, 1 } },
+		// Piece 0 is all there and checks out.
+		{ "after piece boundary", 18000, []int{ 1 } },
+	}
+
+	for _, test := range cases {
+		t.Run(test.description, func(t *testing.T) {
+			directory, info := setup(t)
+			defer os.RemoveAll(directory)
+
+			if err := os.Truncate(filepath.Join(directory, "data", "a"), test.size); err != nil {
+				t.Fatal(err)
+			}
+
+			report := verify(t, directory, info)
+			check(t, report, []int{}, test.short, []int{}, STATUS_SHORT, STATUS_OK, STATUS_UNVERIFIED, STATUS_OK)
+			if file := report.Files[0]; file.Size != test.size || file.BadPieces != len(test.short) {
+				t.Errorf("Unexpected report for short file: %+v", file)
+			}
+		})
+	}
+}
+
+func TestCorruptByte(t *testing.T) {
+	directory, info := setup(t)
+	defer os.RemoveAll(directory)
+
+	// Piece 2 lies entirely within 'c'.
+	path := filepath.Join(directory, "data", "c")
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	contents[2 * 16384 - 20000 + 100] ^= 0xff
+	if err := ioutil.WriteFile(path, contents, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	report := verify(t, directory, info)
+	check(t, report, []int{}, []int{}, []int{ 2 }, STATUS_OK, STATUS_OK, STATUS_CORRUPT, STATUS_OK)
+	if file := report.Files[2]; file.BadPieces != 1 {
+		t.Errorf("Expected 1 bad piece in 'c', got %d", file.BadPieces)
+	}
+}
+
+func TestZeroLengthFile(t *testing.T) {
+	directory, info := setup(t)
+	defer os.RemoveAll(directory)
+
+	if err := os.Remove(filepath.Join(directory, "data", "b")); err != nil {
+		t.Fatal(err)
+	}
+
+	// Empty file has no pieces, but its absence is still reported.
+	report := verify(t, directory, info)
+	check(t, report, []int{}, []int{}, []int{}, STATUS_OK, STATUS_MISSING, STATUS_OK, STATUS_OK)
+	if file := report.Files[1]; file.BadPieces != 0 {
+		t.Errorf("Expected no bad pieces in an empty file, got %d", file.BadPieces)
+	}
+}
+
+func TestUnverifiedNeighbour(t *testing.T) {
+	directory, info := setup(t)
+	defer os.RemoveAll(directory)
+
+	// 'd' has nothing, so the piece it shares with 'c' fails, though the part
+	// of it in 'c' is intact.
+	if err := os.Truncate(filepath.Join(directory, "data", "d"), 0); err != nil {
+		t.Fatal(err)
+	}
+
+	report := verify(t, directory, info)
+	check(t, report, []int{}, []int{ 3 }, []int{}, STATUS_OK, STATUS_OK, STATUS_UNVERIFIED, STATUS_SHORT)
+
+	bad := report.BadFiles()
+	if len(bad) != 2 || bad[0].Path != "data/c" || bad[1].Path != "data/d" {
+		t.Errorf("Expected 'c' and 'd' to be bad, got %+v", bad)
+	}
+}
@@ -0,0 +1,274 @@
+package windows
+
+import (
+	"fmt"
+	"transmission"
+	"tui"
+	"utils"
+	"list"
+	"transform"
+	"metainfo"
+)
+
+const ADD_FILES_HEADER_HEIGHT = 4
+const ADD_FILES_CONTROLS_TEXT = "RETURN - Add torrent | ESC - Back | F1 - Help"
+
+type AddFilesState struct {
+	Info *metainfo.MetaInfo
+	List list.List
+	Obfuscated bool
+	Error error
+}
+
+// Lets the user pick wanted files and priorities of a local torrent before
+// it's added. Files are numbered by their index in the metainfo.
+type AddFilesWindow struct {
+	window tui.Drawable
+	manager *WindowManager
+	state *AddFilesState
+	onConfirm func([]transmission.TorrentFile) error
+}
+
+func (window *AddFilesWindow) IsFullScreen() bool {
+	return true
+}
+
+func (window *AddFilesWindow) SetActive(active bool) {
+	tui.HideCursor()
+}
+
+func (window *AddFilesWindow) OnInput(key tui.Key) {
+	state := window.state
+
+	if key.Rune != nil {
+		switch *key.Rune {
+		case 'q', 'h':
+			window.manager.RemoveWindow(window)
+			return
+		case ' ':
+			state.List.Select()
+		case 'j':
+			state.List.MoveCursor(1)
+		case 'k':
+			state.List.MoveCursor(-1)
+		case 'c':
+			state.List.ClearSelection()
+		case 'A':
+			state.List.SelectAll()
+		case 'i':
+			state.List.InvertSelection()
+		case 'p':
+			// Change priority.
+			items := state.List.GetSelection()
+			if len(items) > 0 {
+				ids, priority := transform.IdsAndNextPriority(transform.ToFileList(items))
+				window.updateFiles(ids, func(file *transmission.TorrentFile) {
+					file.Priority = priority
+				})
+			}
+		case 'g':
+			// Change 'wanted' status.
+			items := state.List.GetSelection()
+			if len(items) > 0 {
+				ids, wanted := transform.IdsAndNextWanted(transform.ToFileList(items))
+				window.updateFiles(ids, func(file *transmission.TorrentFile) {
+					file.Wanted = wanted
+				})
+
+				// If there's no custom selection, move current cursor down.
+				if len(items) == 1 && len(state.List.Selection) == 0 {
+					state.List.MoveCursor(1)
+				}
+			}
+		}
+	} else if key.ControlCode != 0 {
+		switch key.ControlCode {
+		case tui.ASC_ENTER:
+			files := transform.ToFileList(state.List.Items)
+			if err := window.onConfirm(files); err != nil {
+				state.Error = err
+			}
+		case tui.ASC_ESC:
+			window.manager.RemoveWindow(window)
+			return
+		}
+	} else if key.EscapeSeq != nil {
+		switch *key.EscapeSeq {
+		case tui.ESC_LEFT:
+			window.manager.RemoveWindow(window)
+			return
+		case tui.ESC_DOWN:
+			state.List.MoveCursor(1)
+		case tui.ESC_UP:
+			state.List.MoveCursor(-1)
+		case tui.ESC_PGUP:
+			state.List.Page(-1)
+		case tui.ESC_PGDOWN:
+			state.List.Page(1)
+		case tui.ESC_F1:
+			showAddFilesCheatsheet(window.window, window.manager)
+		}
+	}
+
+	go func() {
+		window.manager.Draw <- true
+	}()
+}
+
+func (window *AddFilesWindow) updateFiles(ids []int, update func(*transmission.TorrentFile)) {
+	items := window.state.List.Items
+	for index, item := range items {
+		file := item.(transmission.TorrentFile)
+		if utils.Contains(ids, file.Number) {
+			update(&file)
+			items[index] = file
+		}
+	}
+}
+
+func (window *AddFilesWindow) Draw() {
+	drawAddFiles(window.window, window.state)
+}
+
+func (window *AddFilesWindow) Resize() {
+	window.window.SetWidth(window.window.Parent().Width())
+	window.window.SetHeight(window.window.Parent().Height())
+}
+
+func NewAddFilesWindow(
+	info *metainfo.MetaInfo,
+	obfuscated bool,
+	parent tui.Drawable,
+	manager *WindowManager,
+	onConfirm func([]transmission.TorrentFile) error,
+) *AddFilesWindow {
+	rows, cols := parent.MaxYX()
+
+	window := parent.Sub(0, 0, rows, cols)
+
+	formatter := func(
+		file interface{},
+		width int,
+		printer func(int, string),
+	) {
+		formatFile(file, width, obfuscated, info.Name, printer)
+	}
+
+	files := make([]transmission.TorrentFile, len(info.Files))
+	for index, file := range info.Files {
+		files[index] = transmission.TorrentFile{
+			Number: index,
+			Length: file.Length,
+			Name: file.Path,
+			Wanted: true,
+			Priority: transmission.TR_PRIORITY_NORMAL,
+		}
+	}
+
+	state := &AddFilesState{
+		Info: info,
+		List: list.List{
+			window,
+			formatter,
+			ADD_FILES_HEADER_HEIGHT+2,
+			DETAILS_FOOTER_HEIGHT,
+			0,
+			0,
+			false,
+			0,
+			[]int{},
+			0,
+			transform.GeneralizeFiles(files),
+		},
+		Obfuscated: obfuscated}
+
+	return &AddFilesWindow{
+		window,
+		manager,
+		state,
+		onConfirm}
+}
+
+/* Drawing */
+
+func drawAddFiles(window tui.Drawable, state *AddFilesState) {
+	window.Erase()
+	_, col := window.MaxYX()
+
+	// Name.
+	tui.WithAttribute(tui.ATTR_BOLD, func() {
+		if state.Obfuscated {
+			window.MovePrint(0, 0, utils.Obfuscate(state.Info.Name))
+		} else {
+			window.MovePrint(0, 0, state.Info.Name)
+		}
+	})
+
+	// Size of the wanted files.
+	var wanted int64
+	var wantedCount int
+	for _, item := range state.List.Items {
+		file := item.(transmission.TorrentFile)
+		if file.Wanted {
+			wanted += file.Length
+			wantedCount += 1
+		}
+	}
+	window.MovePrint(1, 0, fmt.Sprintf(
+		"Selected: %s of %s | Files: %d of %d",
+		formatSize(wanted),
+		formatSize(state.Info.TotalSize()),
+		wantedCount,
+		len(state.List.Items)))
+
+	// Controls reminder.
+	window.MovePrint(2, 0, ADD_FILES_CONTROLS_TEXT)
+
+	// Separator.
+	window.HLine(3, 0, col)
+
+	drawFilesLegend(window, ADD_FILES_HEADER_HEIGHT, col)
+
+	// Draw List.
+	state.List.Draw()
+
+	// Draw Error.
+	drawError(window, state.Error)
+}
+
+func showAddFilesCheatsheet(parent tui.Drawable, manager *WindowManager) {
+	items := []HelpItem{
+		HelpItem{ "RETURN", "Add the torrent with chosen files" },
+		HelpItem{ "qh←ESC", "Go back to the add dialog" },
+		HelpItem{ "jk↑↓", "Move cursor up and down" },
+		HelpItem{ "Space", "Toggle selection" },
+		HelpItem{ "c", "Clear selection" },
+		HelpItem{ "A", "Select all items" },
+		HelpItem{ "i", "Invert selection" },
+		HelpItem{ "g", "Download/Don't download selected file(s)" },
+		HelpItem{ "p", "Change priority of selected file(s)" },
+	}
+
+	cheatsheet := NewCheatsheet(parent, items, manager)
+	manager.AddWindow(cheatsheet)
+}
+
+/* Options */
+
+// Fills in file selection options. Wanted files with normal priority are
+// daemon's defaults, so only the rest is sent.
+func applyFileChoices(options *transmission.AddOptions, files []transmission.TorrentFile) {
+	for _, file := range files {
+		if !file.Wanted {
+			options.FilesUnwanted = append(options.FilesUnwanted, file.Number)
+		}
+
+		switch file.Priority {
+		case transmission.TR_PRIORITY_HIGH:
+			options.PriorityHigh = append(options.PriorityHigh, file.Number)
+		case transmission.TR_PRIORITY_LOW:
+			options.PriorityLow = append(options.PriorityLow, file.Number)
+		}
+	}
+}
+
@@ -1,11 +1,15 @@
 package windows
 
 import (
+	"errors"
 	"fmt"
+	"strings"
 	"tui"
 	"utils"
 	"suggestions"
 	"transmission"
+	"metainfo"
+	"magnet"
 )
 
 type NewTorResult int
@@ -19,17 +23,43 @@ const (
 type NewTorrentWindowState struct {
 	UrlField *InputField
 	PathField *InputField
+	LabelsField *InputField
+	Paused bool
+	Priority int
+	// Labels field is skipped for daemons without labels support.
+	LabelsSupported bool
 	Focus int
 	Result NewTorResult
+	// Contents of the local .torrent file in the URL field, if any.
+	Summary *metainfo.MetaInfo
+	SummaryError error
+	SummaryPath string
+	// Parsed magnet link in the URL field, if any.
+	Magnet *magnet.Magnet
+	MagnetError error
 }
 
 const (
 	FOCUS_URL int = 0
 	FOCUS_PATH = 1
-	FOCUS_CONFIRM = 2
-	FOCUS_CANCEL = 3
+	FOCUS_LABELS = 2
+	FOCUS_PAUSED = 3
+	FOCUS_PRIORITY = 4
+	FOCUS_CONFIRM = 5
+	FOCUS_CANCEL = 6
+	FOCUS_COUNT = 7
 )
 
+// Rows reserved for the torrent file summary.
+const ADD_SUMMARY_HEIGHT = 5
+
+// Bandwidth priorities in selector order.
+var addPriorities = []int{
+	transmission.TR_PRIORITY_LOW,
+	transmission.TR_PRIORITY_NORMAL,
+	transmission.TR_PRIORITY_HIGH,
+}
+
 /* Window */
 
 type AddTorrentWindow struct {
@@ -38,6 +68,7 @@ type AddTorrentWindow struct {
 	window tui.Drawable
 	manager *WindowManager
 	state *NewTorrentWindowState
+	obfuscated bool
 	onError func(error)
 }
 
@@ -47,18 +78,30 @@ func (window *AddTorrentWindow) IsFullScreen() bool {
 
 func (window *AddTorrentWindow) SetActive(active bool) {
 	if active {
-		window.state.UrlField.IsActive = true
-		window.manager.AddInputReader(window.state.UrlField)
+		if field := window.focusedField(); field != nil {
+			field.IsActive = true
+			window.manager.AddInputReader(field)
+		}
 	} else {
-		if window.state.Focus == FOCUS_URL {
-			window.manager.RemoveInputReader(window.state.UrlField)
-		} else if window.state.Focus == FOCUS_PATH {
-			window.manager.RemoveInputReader(window.state.PathField)
+		if field := window.focusedField(); field != nil {
+			window.manager.RemoveInputReader(field)
 		}
 		tui.HideCursor()
 	}
 }
 
+func (window *AddTorrentWindow) focusedField() *InputField {
+	switch window.state.Focus {
+	case FOCUS_URL:
+		return window.state.UrlField
+	case FOCUS_PATH:
+		return window.state.PathField
+	case FOCUS_LABELS:
+		return window.state.LabelsField
+	}
+	return nil
+}
+
 func (dialog *AddTorrentWindow) Draw() {
 	window, state := dialog.window, dialog.state
 
@@ -79,34 +122,55 @@ func (dialog *AddTorrentWindow) Draw() {
 	window.MovePrintf(5, startX, "Download path:")
 	state.PathField.Draw()
 
+	// Labels
+	if state.LabelsSupported {
+		window.MovePrint(7, startX, "Labels, comma-separated:")
+		state.LabelsField.Draw()
+	} else {
+		window.WithAttribute(tui.ATTR_DIM, func() {
+			window.MovePrint(7, startX, "Labels: not supported by the daemon")
+		})
+	}
+
+	// Options
+	checkbox := "[ ]"
+	if state.Paused {
+		checkbox = "[x]"
+	}
+	drawFocusable(window, state.Focus == FOCUS_PAUSED, 9, startX, fmt.Sprintf("%s Start paused", checkbox))
+	drawFocusable(
+		window,
+		state.Focus == FOCUS_PRIORITY,
+		10, startX,
+		fmt.Sprintf("Bandwidth priority: < %s >", formatPriority(state.Priority)))
+
+	// Summary
+	window.HLine(11, 1, col-2)
+	dialog.drawSummary(12, startX, width)
+
 	// Controls delimiter
-	window.HLine(7, 1, col-2)
+	controlsRow := 12 + ADD_SUMMARY_HEIGHT
+	window.HLine(controlsRow, 1, col-2)
 
 	buttonWidth := width / 2
-	var attribute []tui.Attribute
 
 	// Confirm
-	if state.Focus == FOCUS_CONFIRM {
-		attribute = []tui.Attribute{tui.ATTR_REVERSED}
-	} else {
-		attribute = []tui.Attribute{}
-	}
-	window.WithAttributes(attribute, func() {
-		window.MovePrint(8, startX + (buttonWidth - len("Confirm")) / 2, "Confirm")
-	})
+	drawFocusable(
+		window,
+		state.Focus == FOCUS_CONFIRM,
+		controlsRow + 1, startX + (buttonWidth - len("Confirm")) / 2,
+		"Confirm")
 
 	// Cancel
-	if state.Focus == FOCUS_CANCEL {
-		attribute = []tui.Attribute{tui.ATTR_REVERSED}
-	} else {
-		attribute = []tui.Attribute{}
-	}
-	window.WithAttributes(attribute, func() {
-		window.MovePrint(8, startX + buttonWidth + (buttonWidth - len("Cancel")) / 2, "Cancel")
-	})
+	drawFocusable(
+		window,
+		state.Focus == FOCUS_CANCEL,
+		controlsRow + 1, startX + buttonWidth + (buttonWidth - len("Cancel")) / 2,
+		"Cancel")
 
 	// Enable cursor on input fields.
-	if (state.Focus < 2) {
+	field := dialog.focusedField()
+	if field != nil {
 		tui.ShowCursor()
 	} else {
 		tui.HideCursor()
@@ -115,18 +179,120 @@ func (dialog *AddTorrentWindow) Draw() {
 	window.Redraw()
 
 	// Move cursor if needed.
-	switch state.Focus {
-	case FOCUS_URL:
-		state.UrlField.SetCursor(window)
-	case FOCUS_PATH:
-		state.PathField.SetCursor(window)
+	if field != nil {
+		field.SetCursor(window)
+	}
+}
+
+func (dialog *AddTorrentWindow) drawSummary(y, x, width int) {
+	window, state := dialog.window, dialog.state
+
+	var lines []string
+	switch {
+	case state.MagnetError != nil:
+		lines = []string{ fmt.Sprintf("Invalid magnet link: %s", state.MagnetError) }
+	case state.Magnet != nil:
+		lines = magnetLines(state.Magnet, dialog.obfuscated)
+	case state.SummaryError != nil:
+		lines = []string{ fmt.Sprintf("Not a valid torrent file: %s", state.SummaryError) }
+	case state.Summary != nil:
+		lines = summaryLines(state.Summary, dialog.obfuscated)
+	default:
+		window.WithAttribute(tui.ATTR_DIM, func() {
+			window.MovePrint(y, x, "Pick a local .torrent file or paste a magnet link to see details")
+		})
+		return
+	}
+
+	for index, line := range lines {
+		if index >= ADD_SUMMARY_HEIGHT {
+			break
+		}
+		window.MovePrint(y + index, x, cropRunes(line, width))
 	}
 }
 
+func summaryLines(info *metainfo.MetaInfo, obfuscated bool) []string {
+	name, comment := info.Name, info.Comment
+	if obfuscated {
+		name, comment = utils.Obfuscate(name), utils.Obfuscate(comment)
+	}
+
+	files := "1 file"
+	if len(info.Files) != 1 {
+		files = fmt.Sprintf("%d files", len(info.Files))
+	}
+
+	trackers := "none"
+	if count := info.TrackerCount(); count > 0 {
+		trackers = fmt.Sprintf("%d in %d tiers", count, len(info.Trackers))
+	}
+	if info.Private {
+		trackers += ", private"
+	}
+
+	lines := []string{
+		fmt.Sprintf("Name: %s", name),
+		fmt.Sprintf(
+			"Size: %s in %s, %d pieces of %s",
+			formatSize(info.TotalSize()),
+			files,
+			info.PieceCount,
+			formatSize(info.PieceLength)),
+		fmt.Sprintf("Info hash: %s", info.InfoHashString()),
+		fmt.Sprintf("Trackers: %s", trackers),
+	}
+	if comment != "" {
+		lines = append(lines, fmt.Sprintf("Comment: %s", comment))
+	}
+	return lines
+}
+
+func magnetLines(link *magnet.Magnet, obfuscated bool) []string {
+	name := link.DisplayName
+	if name == "" {
+		name = "unknown until metadata arrives"
+	} else if obfuscated {
+		name = utils.Obfuscate(name)
+	}
+
+	lines := []string{ fmt.Sprintf("Magnet link: %s", name) }
+	if link.InfoHash != "" {
+		lines = append(lines, fmt.Sprintf("Info hash: %s", link.InfoHash))
+	}
+	if link.InfoHashV2 != "" {
+		lines = append(lines, fmt.Sprintf("Info hash v2: %s", link.InfoHashV2))
+	}
+	if link.Length > 0 {
+		lines = append(lines, fmt.Sprintf("Size: %s", formatSize(link.Length)))
+	}
+	lines = append(lines, fmt.Sprintf("Trackers: %d, web seeds: %d", len(link.Trackers), len(link.WebSeeds)))
+	return lines
+}
+
+func cropRunes(text string, length int) string {
+	runes := []rune(text)
+	if len(runes) > length {
+		return string(runes[:length])
+	}
+	return text
+}
+
+func drawFocusable(window tui.Drawable, focused bool, y, x int, text string) {
+	attributes := []tui.Attribute{}
+	if focused {
+		attributes = []tui.Attribute{tui.ATTR_REVERSED}
+	}
+	window.WithAttributes(attributes, func() {
+		window.MovePrint(y, x, text)
+	})
+}
+
 func (window *AddTorrentWindow) Resize() {
 	height, width, y, x := MeasureAddTorrentWindow(window.parent)
 	window.state.UrlField.Length = width - 4
 	window.state.PathField.Length = width - 4
+	window.state.LabelsField.Length = width - 4
 	window.window.Move(y, x)
 	window.window.Resize(height, width)
 }
@@ -134,40 +300,240 @@ func (window *AddTorrentWindow) Resize() {
 func MeasureAddTorrentWindow(parent tui.Drawable) (int, int, int, int) {
 	rows, cols := parent.MaxYX()
 
-	height, width := 10, utils.MinInt(cols, utils.MaxInt(60, cols * 3 / 4))
+	height, width := 15 + ADD_SUMMARY_HEIGHT, utils.MinInt(cols, utils.MaxInt(60, cols * 3 / 4))
 	y, x := (rows - height) / 2, (cols - width) / 2
 	return height, width, y, x
 }
 
 func (window *AddTorrentWindow) OnInput(key tui.Key) {
+	state := window.state
+
 	if key.ControlCode != 0 {
 		switch key.ControlCode {
 		case tui.ASC_TAB:
 			window.UpdateFocus(nil, 1)
 		case tui.ASC_ENTER:
-			if window.state.Focus == FOCUS_CANCEL {
+			if state.Focus == FOCUS_CANCEL {
 				window.manager.RemoveWindow(window)
 			} else {
-				url, path := utils.ExpandHome(string(window.state.UrlField.Value)), utils.ExpandHome(string(window.state.PathField.Value))
-				err := window.client.AddTorrent(url, path)
-				if err != nil {
-					window.onError(fmt.Errorf("Error: %s", err))
-				} else {
-					window.manager.RemoveWindow(window)
-				}
+				window.addTorrent()
 			}
 		}
+	} else if key.Rune != nil && *key.Rune == ' ' {
+		switch state.Focus {
+		case FOCUS_PAUSED:
+			state.Paused = !state.Paused
+			window.redraw()
+		case FOCUS_PRIORITY:
+			window.changePriority(1)
+		}
 	} else if key.EscapeSeq != nil {
 		switch *key.EscapeSeq {
-		case tui.ESC_UP, tui.ESC_LEFT:
+		case tui.ESC_LEFT:
+			if state.Focus == FOCUS_PRIORITY {
+				window.changePriority(-1)
+			} else {
+				window.UpdateFocus(nil, -1)
+			}
+		case tui.ESC_RIGHT:
+			if state.Focus == FOCUS_PRIORITY {
+				window.changePriority(1)
+			} else {
+				window.UpdateFocus(nil, 1)
+			}
+		case tui.ESC_UP:
 			window.UpdateFocus(nil, -1)
-		case tui.ESC_DOWN, tui.ESC_RIGHT:
+		case tui.ESC_DOWN:
 			window.UpdateFocus(nil, 1)
 		}
 	}
 }
 
+func (window *AddTorrentWindow) addTorrent() {
+	state := window.state
+
+	url := utils.ExpandHome(string(state.UrlField.Value))
+	options := transmission.AddOptions{
+		DownloadDir: utils.ExpandHome(string(state.PathField.Value)),
+		Paused: state.Paused,
+		BandwidthPriority: state.Priority,
+	}
+	if state.LabelsSupported {
+		options.Labels = parseLabels(string(state.LabelsField.Value))
+	}
+
+	// Let the user pick files of a multi-file torrent first.
+	if info := state.Summary; info != nil && len(info.Files) > 1 {
+		var picker *AddFilesWindow
+		picker = NewAddFilesWindow(
+			info,
+			window.obfuscated,
+			window.parent,
+			window.manager,
+			func(files []transmission.TorrentFile) error {
+				chosen := options
+				applyFileChoices(&chosen, files)
+
+				result, err := window.client.AddTorrentWithOptions(url, chosen)
+				if err != nil {
+					return describeAddError(err)
+				}
+
+				window.manager.RemoveWindow(picker)
+				window.finish(result)
+				return nil
+			})
+		window.manager.AddWindow(picker)
+		return
+	}
+
+	// Magnet links have no files until metadata arrives. Keep the torrent
+	// paused until the user picks them, the metadata window only runs it
+	// while fetching.
+	isMagnet := isMagnetLink(url)
+	if isMagnet {
+		link, err := magnet.Parse(url)
+		if err != nil {
+			window.onError(fmt.Errorf("Invalid magnet link: %s", err))
+			return
+		}
+
+		// Hex hashes only, not every daemon understands base32 ones.
+		url = link.String()
+		options.Paused = true
+	}
+
+	result, err := window.client.AddTorrentWithOptions(url, options)
+	if err != nil {
+		window.onError(describeAddError(err))
+		return
+	}
+
+	if isMagnet && !result.Duplicate {
+		window.manager.RemoveWindow(window)
+		window.waitForMetadata(result.Torrent)
+		return
+	}
+
+	window.finish(result)
+}
+
+// Shows metadata progress, then the torrent's files. Torrent starts after
+// the user confirms them, unless it was meant to be added paused.
+func (window *AddTorrentWindow) waitForMetadata(torrent transmission.TorrentAddedInfo) {
+	start := !window.state.Paused
+
+	metadata := NewMetadataWindow(
+		window.client,
+		torrent,
+		window.obfuscated,
+		window.parent,
+		window.manager,
+		func() {
+			files := NewTorrentFilesWindow(window.client, torrent.Id, start, window.obfuscated, window.parent, window.manager)
+			window.manager.AddWindow(files)
+		})
+	window.manager.AddWindow(metadata)
+}
+
+func (window *AddTorrentWindow) finish(result *transmission.AddResult) {
+	window.manager.RemoveWindow(window)
+	if result.Duplicate {
+		window.offerDetails(result.Torrent)
+	}
+}
+
+// Daemon already has the torrent, nothing was added.
+func (window *AddTorrentWindow) offerDetails(torrent transmission.TorrentAddedInfo) {
+	name := torrent.Name
+	if window.obfuscated {
+		name = utils.Obfuscate(name)
+	}
+
+	ConfirmPrompt(
+		window.parent,
+		window.manager,
+		fmt.Sprintf("'%s' is already added. Open its details?", name),
+		func() {
+			details := NewTorrentDetailsWindow(
+				window.client,
+				torrent.Id,
+				window.obfuscated,
+				window.parent,
+				window.manager,
+			)
+			window.manager.AddWindow(details)
+		})
+}
+
+// Cycles through priorities without wrapping around.
+func (window *AddTorrentWindow) changePriority(direction int) {
+	index := 0
+	for i, priority := range addPriorities {
+		if priority == window.state.Priority {
+			index = i
+		}
+	}
+
+	index = utils.MaxInt(0, utils.MinInt(len(addPriorities) - 1, index + direction))
+	window.state.Priority = addPriorities[index]
+	window.redraw()
+}
+
+func (window *AddTorrentWindow) redraw() {
+	go func() {
+		window.manager.Draw <- true
+	}()
+}
+
+// Comma-separated list, with blanks and duplicates removed.
+func parseLabels(value string) []string {
+	labels := []string{}
+	for _, label := range strings.Split(value, ",") {
+		label = strings.TrimSpace(label)
+		if label != "" && utils.IndexOf(labels, label) < 0 {
+			labels = append(labels, label)
+		}
+	}
+	return labels
+}
+
+// Parses the URL field's magnet link, or its file when it changes to
+// a different local file.
+func (window *AddTorrentWindow) updateSummary() {
+	state := window.state
+
+	source := utils.ExpandHome(string(state.UrlField.Value))
+	if isMagnetLink(source) {
+		state.Summary, state.SummaryError, state.SummaryPath = nil, nil, ""
+		state.Magnet, state.MagnetError = magnet.Parse(source)
+		return
+	}
+	state.Magnet, state.MagnetError = nil, nil
+
+	path, ok := transmission.LocalTorrentPath(source)
+	if !ok {
+		state.Summary, state.SummaryError, state.SummaryPath = nil, nil, ""
+		return
+	}
+
+	if path == state.SummaryPath {
+		return
+	}
+
+	state.Summary, state.SummaryError = metainfo.Load(path)
+	state.SummaryPath = path
+}
+
+func isMagnetLink(source string) bool {
+	return strings.HasPrefix(strings.ToLower(strings.TrimSpace(source)), "magnet:")
+}
+
 func (window *AddTorrentWindow) HandleInputFieldUpdate(field *InputField, result InputFieldResult) {
+	if field == window.state.UrlField {
+		window.updateSummary()
+	}
+
 	switch result {
 	case FOCUS_FORWARD:
 		window.UpdateFocus(field, 1)
@@ -186,34 +552,37 @@ func (window *AddTorrentWindow) UpdateFocus(source *InputField, direction int) {
 		window.manager.RemoveInputReader(source)
 	}
 
-	if direction > 0 {
-		window.state.Focus = (window.state.Focus + direction) % 4
-	} else {
-		window.state.Focus = window.state.Focus + direction
-		if window.state.Focus < 0 {
-			window.state.Focus = 3
+	// Step over labels field if it's unavailable.
+	for {
+		window.state.Focus = (window.state.Focus + direction + FOCUS_COUNT) % FOCUS_COUNT
+		if window.state.Focus != FOCUS_LABELS || window.state.LabelsSupported {
+			break
 		}
 	}
 
-	var newInput *InputField
-	switch window.state.Focus {
-	case FOCUS_URL:
-		newInput = window.state.UrlField
-	case FOCUS_PATH:
-		newInput = window.state.PathField
-	}
-
-	if newInput != nil {
+	if newInput := window.focusedField(); newInput != nil {
 		newInput.IsActive = true
 		window.manager.AddInputReader(newInput)
 	}
 
-	go func() {
-		window.manager.Draw <- true
-	}()
+	window.redraw()
 }
 
-func NewAddTorrentWindow(client *transmission.Client, parent tui.Drawable, manager *WindowManager, onError func(error)) *AddTorrentWindow {
+func describeAddError(err error) error {
+	var rpcError *transmission.RPCError
+	if errors.As(err, &rpcError) {
+		return fmt.Errorf("Daemon rejected the torrent: %s", rpcError.Result)
+	}
+	return err
+}
+
+func NewAddTorrentWindow(
+	client *transmission.Client,
+	parent tui.Drawable,
+	manager *WindowManager,
+	obfuscated bool,
+	onError func(error),
+) *AddTorrentWindow {
 	height, width, y, x := MeasureAddTorrentWindow(parent)
 	window := parent.Sub(y, x, height, width)
 
@@ -245,6 +614,21 @@ func NewAddTorrentWindow(client *transmission.Client, parent tui.Drawable, manag
 			Parent: window,
 			OnResult: nil,
 		},
+		LabelsField: &InputField{
+			X: 2, Y: 8, Length: width - 4,
+			IsModal: false,
+			EnterToConfirm: false,
+			IsActive: false,
+			Value: []rune{},
+			Charset: "",
+			Suggester: nil,
+			Suggestion: nil,
+			Manager: manager,
+			Parent: window,
+			OnResult: nil,
+		},
+		Priority: transmission.TR_PRIORITY_NORMAL,
+		LabelsSupported: client.Supports(transmission.FEATURE_LABELS),
 	}
 
 	dialog := &AddTorrentWindow{
@@ -253,11 +637,13 @@ func NewAddTorrentWindow(client *transmission.Client, parent tui.Drawable, manag
 		window,
 		manager,
 		state,
+		obfuscated,
 		onError}
 
 	// Hook up input field listeners.
 	state.UrlField.OnResult = dialog.HandleInputFieldUpdate
 	state.PathField.OnResult = dialog.HandleInputFieldUpdate
+	state.LabelsField.OnResult = dialog.HandleInputFieldUpdate
 
 	return dialog
 }
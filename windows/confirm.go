@@ -0,0 +1,169 @@
+package windows
+
+import (
+	"tui"
+	"utils"
+)
+
+/* Data */
+
+const CONFIRM_CONTROLS_TEXT = "y - Yes | n, ESC - No"
+
+type ConfirmState struct {
+	Message string
+	// True when 'Yes' is focused.
+	Yes bool
+}
+
+/* Window */
+
+type Confirm struct {
+	parent tui.Drawable
+	window tui.Drawable
+	manager *WindowManager
+	state *ConfirmState
+	onYes func()
+	onNo func()
+}
+
+func (window *Confirm) OnInput(key tui.Key) {
+	if key.Rune != nil {
+		switch *key.Rune {
+		case 'y', 'Y':
+			window.onYes()
+		case 'n', 'N':
+			window.onNo()
+		}
+	} else if key.ControlCode != 0 {
+		switch key.ControlCode {
+		case tui.ASC_ENTER:
+			if window.state.Yes {
+				window.onYes()
+			} else {
+				window.onNo()
+			}
+		case tui.ASC_ESC:
+			window.onNo()
+		case tui.ASC_TAB:
+			window.toggle()
+		}
+	} else if key.EscapeSeq != nil {
+		switch *key.EscapeSeq {
+		case tui.ESC_LEFT, tui.ESC_RIGHT:
+			window.toggle()
+		}
+	}
+}
+
+func (window *Confirm) toggle() {
+	window.state.Yes = !window.state.Yes
+	go func() {
+		window.manager.Draw <- true
+	}()
+}
+
+func (window *Confirm) SetActive(active bool) {
+	tui.HideCursor()
+}
+
+func (window *Confirm) IsFullScreen() bool {
+	return false
+}
+
+func (window *Confirm) Draw() {
+	window.window.Box()
+
+	_, col := window.window.MaxYX()
+	startX, width := 2, col-4
+
+	// Message.
+	message := []rune(window.state.Message)
+	if len(message) > width {
+		message = message[:width]
+	}
+	window.window.MovePrint(1, startX, string(message))
+
+	// Buttons.
+	buttonWidth := width / 2
+	drawFocusable(
+		window.window,
+		window.state.Yes,
+		2, startX + (buttonWidth - len("Yes")) / 2,
+		"Yes")
+	drawFocusable(
+		window.window,
+		!window.state.Yes,
+		2, startX + buttonWidth + (buttonWidth - len("No")) / 2,
+		"No")
+
+	// Delimiter.
+	window.window.HLine(3, 1, col-2)
+
+	// Controls reminder.
+	window.window.MovePrint(4, startX + (width - len(CONFIRM_CONTROLS_TEXT)) / 2, CONFIRM_CONTROLS_TEXT)
+
+	// Trigger screen refresh.
+	window.window.Redraw()
+}
+
+func (window *Confirm) Resize() {
+	height, width, y, x := MeasureConfirm(window.parent, window.state.Message)
+	window.window.Move(y, x)
+	window.window.Resize(height, width)
+}
+
+func MeasureConfirm(parent tui.Drawable, message string) (int, int, int, int) {
+	rows, cols := parent.MaxYX()
+
+	maxWidth := utils.MinInt(cols, utils.MaxInt(60, cols * 3 / 4))
+	width := utils.MinInt(maxWidth, utils.MaxInt(len([]rune(message)), len(CONFIRM_CONTROLS_TEXT)) + 4)
+
+	height := 6
+	y, x := (rows - height) / 2, (cols - width) / 2
+
+	return height, width, y, x
+}
+
+func NewConfirm(
+	parent tui.Drawable,
+	manager *WindowManager,
+	message string,
+	onYes func(),
+	onNo func(),
+) *Confirm {
+	height, width, y, x := MeasureConfirm(parent, message)
+	window := parent.Sub(y, x, height, width)
+
+	return &Confirm{
+		parent,
+		window,
+		manager,
+		&ConfirmState{ message, true },
+		onYes,
+		onNo,
+	}
+}
+
+/* Public helpers */
+
+// Shows a yes/no question. Closes itself before calling either handler.
+func ConfirmPrompt(
+	parent tui.Drawable,
+	manager *WindowManager,
+	message string,
+	onYes func(),
+) {
+	var confirm *Confirm
+	confirm = NewConfirm(
+		parent,
+		manager,
+		message,
+		func() {
+			manager.RemoveWindow(confirm)
+			onYes()
+		},
+		func() {
+			manager.RemoveWindow(confirm)
+		})
+	manager.AddWindow(confirm)
+}
@@ -0,0 +1,506 @@
+package windows
+
+import (
+	"context"
+	"creator"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"suggestions"
+	"sync/atomic"
+	"time"
+	"transmission"
+	"tui"
+	"utils"
+)
+
+const (
+	CREATE_FOCUS_SOURCE int = 0
+	CREATE_FOCUS_OUTPUT = 1
+	CREATE_FOCUS_TRACKERS = 2
+	CREATE_FOCUS_WEBSEEDS = 3
+	CREATE_FOCUS_COMMENT = 4
+	CREATE_FOCUS_PRIVATE = 5
+	CREATE_FOCUS_PIECE = 6
+	CREATE_FOCUS_CONFIRM = 7
+	CREATE_FOCUS_CANCEL = 8
+	CREATE_FOCUS_COUNT = 9
+)
+
+const CREATED_BY = "transmission-go"
+
+// Piece sizes in selector order, zero means automatic.
+var createPieceLengths = []int64{
+	0,
+	16 * 1024, 32 * 1024, 64 * 1024, 128 * 1024, 256 * 1024, 512 * 1024,
+	1024 * 1024, 2 * 1024 * 1024, 4 * 1024 * 1024, 8 * 1024 * 1024, 16 * 1024 * 1024,
+}
+
+type CreateTorrentState struct {
+	SourceField *InputField
+	OutputField *InputField
+	TrackersField *InputField
+	WebSeedsField *InputField
+	CommentField *InputField
+	Private bool
+	PieceLength int64
+	Focus int
+
+	// Hashing progress, updated from hashing goroutines.
+	Creating bool
+	Hashed int64
+	Total int64
+	cancel context.CancelFunc
+
+	// Path of the created torrent, and of the data it describes.
+	Created string
+	Source string
+	Error error
+	// Output file that already exists, waiting for the user to confirm
+	// overwriting it.
+	existing string
+}
+
+/* Window */
+
+type CreateTorrentWindow struct {
+	client *transmission.Client
+	parent tui.Drawable
+	window tui.Drawable
+	manager *WindowManager
+	state *CreateTorrentState
+}
+
+func (window *CreateTorrentWindow) IsFullScreen() bool {
+	return false
+}
+
+func (window *CreateTorrentWindow) SetActive(active bool) {
+	field := window.focusedField()
+	if field == nil {
+		tui.HideCursor()
+		return
+	}
+
+	if active {
+		field.IsActive = true
+		window.manager.AddInputReader(field)
+	} else {
+		window.manager.RemoveInputReader(field)
+		tui.HideCursor()
+	}
+}
+
+func (window *CreateTorrentWindow) fields() []*InputField {
+	state := window.state
+	return []*InputField{
+		state.SourceField,
+		state.OutputField,
+		state.TrackersField,
+		state.WebSeedsField,
+		state.CommentField,
+	}
+}
+
+func (window *CreateTorrentWindow) focusedField() *InputField {
+	fields := window.fields()
+	if window.state.Focus < len(fields) {
+		return fields[window.state.Focus]
+	}
+	return nil
+}
+
+func (dialog *CreateTorrentWindow) Draw() {
+	window, state := dialog.window, dialog.state
+
+	// Prompts have to be added on the UI loop, and drawing is the first
+	// chance after hashing refused the file.
+	if state.existing != "" {
+		existing := state.existing
+		state.existing = ""
+		ConfirmPrompt(
+			dialog.parent,
+			dialog.manager,
+			fmt.Sprintf("%s already exists. Overwrite it?", existing),
+			func() {
+				dialog.create(true)
+			})
+	}
+
+	window.Box()
+
+	_, col := window.MaxYX()
+	startX, width := 2, col-4
+
+	// Header
+	window.MovePrint(1, startX, "Create torrent")
+	window.HLine(2, 1, col-2)
+
+	// Fields
+	labels := []string{
+		"File or directory to share:",
+		"Save torrent as (empty for <source>.torrent):",
+		"Trackers, comma-separated, one tier each:",
+		"Web seeds, comma-separated:",
+		"Comment:",
+	}
+	for index, field := range dialog.fields() {
+		window.MovePrint(field.Y - 1, startX, labels[index])
+		field.Draw()
+	}
+
+	// Options
+	checkbox := "[ ]"
+	if state.Private {
+		checkbox = "[x]"
+	}
+	drawFocusable(window, state.Focus == CREATE_FOCUS_PRIVATE, 13, startX, fmt.Sprintf("%s Private", checkbox))
+
+	pieceLength := "Auto"
+	if state.PieceLength > 0 {
+		pieceLength = formatSize(state.PieceLength)
+	}
+	drawFocusable(
+		window,
+		state.Focus == CREATE_FOCUS_PIECE,
+		14, startX,
+		fmt.Sprintf("Piece size: < %s >", pieceLength))
+
+	// Status
+	window.HLine(15, 1, col-2)
+	dialog.drawStatus(16, startX, width)
+	window.HLine(17, 1, col-2)
+
+	// Buttons
+	confirm, cancel := "Create", "Cancel"
+	if state.Created != "" {
+		confirm, cancel = "Add & seed", "Close"
+	}
+
+	buttonWidth := width / 2
+	drawFocusable(
+		window,
+		state.Focus == CREATE_FOCUS_CONFIRM && !state.Creating,
+		18, startX + (buttonWidth - len(confirm)) / 2,
+		confirm)
+	drawFocusable(
+		window,
+		state.Focus == CREATE_FOCUS_CANCEL || state.Creating,
+		18, startX + buttonWidth + (buttonWidth - len(cancel)) / 2,
+		cancel)
+
+	// Enable cursor on input fields.
+	field := dialog.focusedField()
+	if field != nil && !state.Creating {
+		tui.ShowCursor()
+	} else {
+		tui.HideCursor()
+	}
+
+	window.Redraw()
+
+	if field != nil && !state.Creating {
+		field.SetCursor(window)
+	}
+}
+
+func (dialog *CreateTorrentWindow) drawStatus(y, x, width int) {
+	window, state := dialog.window, dialog.state
+
+	switch {
+	case state.Creating:
+		hashed, total := atomic.LoadInt64(&state.Hashed), atomic.LoadInt64(&state.Total)
+
+		var progress float64
+		if total > 0 {
+			progress = float64(hashed) / float64(total)
+		}
+
+		window.MovePrint(y, x, formatProgress(progress, width))
+	case state.Error != nil:
+		window.MovePrint(y, x, cropRunes(fmt.Sprintf("%s", state.Error), width))
+	case state.Created != "":
+		message := fmt.Sprintf("Saved %s. Add it to seed from %s?", state.Created, filepath.Dir(state.Source))
+		window.MovePrint(y, x, cropRunes(message, width))
+	default:
+		window.WithAttribute(tui.ATTR_DIM, func() {
+			window.MovePrint(y, x, "Pieces are hashed locally, the daemon isn't involved")
+		})
+	}
+}
+
+func (window *CreateTorrentWindow) Resize() {
+	height, width, y, x := MeasureCreateTorrentWindow(window.parent)
+	for _, field := range window.fields() {
+		field.Length = width - 4
+	}
+	window.window.Move(y, x)
+	window.window.Resize(height, width)
+}
+
+func MeasureCreateTorrentWindow(parent tui.Drawable) (int, int, int, int) {
+	rows, cols := parent.MaxYX()
+
+	height, width := 20, utils.MinInt(cols, utils.MaxInt(60, cols * 3 / 4))
+	y, x := (rows - height) / 2, (cols - width) / 2
+	return height, width, y, x
+}
+
+func (window *CreateTorrentWindow) OnInput(key tui.Key) {
+	state := window.state
+
+	// Only cancelling is possible while hashing.
+	if state.Creating {
+		if key.ControlCode == tui.ASC_ESC || key.ControlCode == tui.ASC_ENTER {
+			state.cancel()
+			window.manager.RemoveWindow(window)
+		}
+		return
+	}
+
+	if key.ControlCode != 0 {
+		switch key.ControlCode {
+		case tui.ASC_TAB:
+			window.UpdateFocus(nil, 1)
+		case tui.ASC_ESC:
+			window.manager.RemoveWindow(window)
+		case tui.ASC_ENTER:
+			switch {
+			case state.Focus == CREATE_FOCUS_CANCEL:
+				window.manager.RemoveWindow(window)
+			case state.Created != "":
+				window.seed()
+			default:
+				window.create(false)
+			}
+		}
+	} else if key.Rune != nil && *key.Rune == ' ' {
+		switch state.Focus {
+		case CREATE_FOCUS_PRIVATE:
+			state.Private = !state.Private
+			window.reset()
+		case CREATE_FOCUS_PIECE:
+			window.changePieceLength(1)
+		}
+	} else if key.EscapeSeq != nil {
+		switch *key.EscapeSeq {
+		case tui.ESC_LEFT:
+			if state.Focus == CREATE_FOCUS_PIECE {
+				window.changePieceLength(-1)
+			} else {
+				window.UpdateFocus(nil, -1)
+			}
+		case tui.ESC_RIGHT:
+			if state.Focus == CREATE_FOCUS_PIECE {
+				window.changePieceLength(1)
+			} else {
+				window.UpdateFocus(nil, 1)
+			}
+		case tui.ESC_UP:
+			window.UpdateFocus(nil, -1)
+		case tui.ESC_DOWN:
+			window.UpdateFocus(nil, 1)
+		}
+	}
+}
+
+// Cycles through piece sizes without wrapping around.
+func (window *CreateTorrentWindow) changePieceLength(direction int) {
+	index := 0
+	for i, length := range createPieceLengths {
+		if length == window.state.PieceLength {
+			index = i
+		}
+	}
+
+	index = utils.MaxInt(0, utils.MinInt(len(createPieceLengths) - 1, index + direction))
+	window.state.PieceLength = createPieceLengths[index]
+	window.reset()
+}
+
+// Starts hashing in the background. Progress is redrawn a few times per
+// second until it's done. Existing output file is only replaced after the
+// user confirms it.
+func (window *CreateTorrentWindow) create(overwrite bool) {
+	state := window.state
+
+	source := strings.TrimSpace(utils.ExpandHome(string(state.SourceField.Value)))
+	if source == "" {
+		state.Error = &Message{ "Pick a file or directory to share" }
+		window.redraw()
+		return
+	}
+
+	output := strings.TrimSpace(utils.ExpandHome(string(state.OutputField.Value)))
+	if output == "" {
+		output = strings.TrimRight(source, string(filepath.Separator)) + ".torrent"
+	}
+
+	trackers := [][]string{}
+	for _, tracker := range parseLabels(string(state.TrackersField.Value)) {
+		trackers = append(trackers, []string{ tracker })
+	}
+
+	options := creator.Options{
+		Path: source,
+		PieceLength: state.PieceLength,
+		Trackers: trackers,
+		WebSeeds: parseLabels(string(state.WebSeedsField.Value)),
+		Comment: strings.TrimSpace(string(state.CommentField.Value)),
+		Private: state.Private,
+		CreatedBy: CREATED_BY,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	state.cancel = cancel
+	state.Creating, state.Error = true, nil
+	atomic.StoreInt64(&state.Hashed, 0)
+	atomic.StoreInt64(&state.Total, 0)
+
+	progress := func(hashed int64, total int64) {
+		atomic.StoreInt64(&state.Hashed, hashed)
+		atomic.StoreInt64(&state.Total, total)
+	}
+
+	done := make(chan error)
+	go func() {
+		done <- creator.CreateFile(ctx, options, output, overwrite, progress)
+	}()
+
+	go func() {
+		ticker := time.NewTicker(200 * time.Millisecond)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				window.manager.Draw <- true
+			case err := <-done:
+				if ctx.Err() != nil {
+					// Cancelled, dialog is gone.
+					return
+				}
+
+				switch {
+				case os.IsExist(err):
+					state.existing = output
+				case err != nil:
+					state.Error = err
+				default:
+					absolute, _ := filepath.Abs(source)
+					state.Created, state.Source = output, absolute
+				}
+				state.Creating = false
+				cancel()
+				window.manager.Draw <- true
+				return
+			}
+		}
+	}()
+
+	window.redraw()
+}
+
+// Adds the created torrent with the data's directory as the download
+// location, so the daemon verifies the files and starts seeding.
+func (window *CreateTorrentWindow) seed() {
+	state := window.state
+
+	result, err := window.client.AddTorrent(state.Created, filepath.Dir(state.Source))
+	if err != nil {
+		state.Error = describeAddError(err)
+		window.redraw()
+		return
+	}
+
+	if result.Duplicate {
+		state.Error = fmt.Errorf("'%s' is already added", result.Torrent.Name)
+		window.redraw()
+		return
+	}
+
+	window.manager.RemoveWindow(window)
+}
+
+// Any change to the options makes the created torrent outdated.
+func (window *CreateTorrentWindow) reset() {
+	window.state.Created, window.state.Error = "", nil
+	window.redraw()
+}
+
+func (window *CreateTorrentWindow) redraw() {
+	go func() {
+		window.manager.Draw <- true
+	}()
+}
+
+func (window *CreateTorrentWindow) HandleInputFieldUpdate(field *InputField, result InputFieldResult) {
+	switch result {
+	case FOCUS_FORWARD:
+		window.UpdateFocus(field, 1)
+	case FOCUS_BACKWARD:
+		window.UpdateFocus(field, -1)
+	case UPDATE:
+		window.reset()
+	}
+}
+
+func (window *CreateTorrentWindow) UpdateFocus(source *InputField, direction int) {
+	if source != nil {
+		source.IsActive = false
+		window.manager.RemoveInputReader(source)
+	}
+
+	window.state.Focus = (window.state.Focus + direction + CREATE_FOCUS_COUNT) % CREATE_FOCUS_COUNT
+
+	if newInput := window.focusedField(); newInput != nil {
+		newInput.IsActive = true
+		window.manager.AddInputReader(newInput)
+	}
+
+	window.redraw()
+}
+
+func NewCreateTorrentWindow(client *transmission.Client, parent tui.Drawable, manager *WindowManager) *CreateTorrentWindow {
+	height, width, y, x := MeasureCreateTorrentWindow(parent)
+	window := parent.Sub(y, x, height, width)
+
+	field := func(y int, suggester Suggester) *InputField {
+		return &InputField{
+			X: 2, Y: y, Length: width - 4,
+			IsModal: false,
+			EnterToConfirm: false,
+			IsActive: false,
+			Value: []rune{},
+			Charset: "",
+			Suggester: suggester,
+			Suggestion: nil,
+			Manager: manager,
+			Parent: window,
+			OnResult: nil,
+		}
+	}
+
+	state := &CreateTorrentState{
+		SourceField: field(4, suggestions.GetSuggestedFiles),
+		OutputField: field(6, suggestions.GetSuggestedFiles),
+		TrackersField: field(8, nil),
+		WebSeedsField: field(10, nil),
+		CommentField: field(12, nil),
+	}
+
+	dialog := &CreateTorrentWindow{
+		client,
+		parent,
+		window,
+		manager,
+		state}
+
+	// Hook up input field listeners.
+	for _, field := range dialog.fields() {
+		field.OnResult = dialog.HandleInputFieldUpdate
+	}
+
+	return dialog
+}
@@ -1,6 +1,7 @@
 package windows
 
 import (
+	"context"
 	"strings"
 	"fmt"
 	"transmission"
@@ -14,6 +15,13 @@ import (
 const DETAILS_HEADER_HEIGHT = 5
 const DETAILS_FOOTER_HEIGHT = 2
 
+const (
+	DETAILS_TAB_FILES int = 0
+	DETAILS_TAB_TRACKERS = 1
+	DETAILS_TAB_PEERS = 2
+	DETAILS_TAB_COUNT = 3
+)
+
 type Message struct {
 	text string
 }
@@ -25,8 +33,18 @@ func (e *Message) Error() string {
 type TorrentDetailsState struct {
 	Torrent *transmission.TorrentDetails
 	List list.List
+	Trackers list.List
+	Peers list.List
+	PeersOrder int
+	Tab int
 	Obfuscated bool
+	// Window is a file selection of a paused torrent, confirmed with RETURN.
+	ConfirmFiles bool
+	// Torrent is started once the file selection is confirmed.
+	StartOnConfirm bool
 	Error error
+	// Tabs fetched at least once. Others keep their last contents.
+	loaded [DETAILS_TAB_COUNT]bool
 }
 
 type TorrentDetailsWindow struct {
@@ -49,8 +67,20 @@ func (window *TorrentDetailsWindow) SetActive(active bool) {
 	}
 }
 
+// List of the current tab.
+func (state *TorrentDetailsState) currentList() *list.List {
+	switch state.Tab {
+	case DETAILS_TAB_TRACKERS:
+		return &state.Trackers
+	case DETAILS_TAB_PEERS:
+		return &state.Peers
+	}
+	return &state.List
+}
+
 func (window *TorrentDetailsWindow) OnInput(key tui.Key) {
 	state := window.state
+	current := state.currentList()
 
 	if key.Rune != nil {
 		switch *key.Rune {
@@ -58,55 +88,34 @@ func (window *TorrentDetailsWindow) OnInput(key tui.Key) {
 			window.manager.RemoveWindow(window)
 			return
 		case ' ':
-			state.List.Select()
+			if len(current.Items) > 0 {
+				current.Select()
+			}
 		case 'j':
-			state.List.MoveCursor(1)
+			current.MoveCursor(1)
 		case 'k':
-			state.List.MoveCursor(-1)
+			current.MoveCursor(-1)
 		case 'c':
-			state.List.ClearSelection()
+			current.ClearSelection()
 		case 'A':
-			state.List.SelectAll()
+			current.SelectAll()
 		case 'i':
-			state.List.InvertSelection()
-		case 'p':
-			// Change priority.
-			items := state.List.GetSelection()
-			if len(items) > 0 {
-				files := transform.ToFileList(items)
-				ids, priority := transform.IdsAndNextPriority(files)
-				go func() {
-					updatePriority(
-						window.client,
-						state.Torrent.Id,
-						ids,
-						priority,
-						state,
-					)
-					window.manager.Draw <- true
-				}()
+			current.InvertSelection()
+		case 'a':
+			if state.Tab == DETAILS_TAB_TRACKERS {
+				promptAddTrackers(window)
 			}
-		case 'g':
-			// Change 'wanted' status.
-			items := state.List.GetSelection()
-			if len(items) > 0 {
-				files := transform.ToFileList(items)
-				ids, wanted := transform.IdsAndNextWanted(files)
-				go func() {
-					updateWanted(
-						window.client,
-						state.Torrent.Id,
-						ids,
-						wanted,
-						state,
-					)
-					window.manager.Draw <- true
-				}()
-
-				// If there's no custom selection, move current cursor down.
-				if len(items) == 1 && len(state.List.Selection) == 0 {
-					state.List.MoveCursor(1)
-				}
+		case 'e':
+			if state.Tab == DETAILS_TAB_TRACKERS {
+				promptReplaceTracker(window)
+			}
+		case 'd':
+			if state.Tab == DETAILS_TAB_TRACKERS {
+				confirmRemoveTrackers(window)
+			}
+		case 's':
+			if state.Tab == DETAILS_TAB_PEERS {
+				changePeersOrder(state)
 			}
 		case 'L':
 			// Change download limit.
@@ -174,6 +183,56 @@ func (window *TorrentDetailsWindow) OnInput(key tui.Key) {
 					state.Error = err
 				},
 			)
+		}
+
+		// Remaining keys work with files.
+		if state.Tab != DETAILS_TAB_FILES {
+			go func() {
+				window.manager.Draw <- true
+			}()
+			return
+		}
+
+		switch *key.Rune {
+		case 'p':
+			// Change priority.
+			items := state.List.GetSelection()
+			if len(items) > 0 {
+				files := transform.ToFileList(items)
+				ids, priority := transform.IdsAndNextPriority(files)
+				go func() {
+					updatePriority(
+						window.client,
+						state.Torrent.Id,
+						ids,
+						priority,
+						state,
+					)
+					window.manager.Draw <- true
+				}()
+			}
+		case 'g':
+			// Change 'wanted' status.
+			items := state.List.GetSelection()
+			if len(items) > 0 {
+				files := transform.ToFileList(items)
+				ids, wanted := transform.IdsAndNextWanted(files)
+				go func() {
+					updateWanted(
+						window.client,
+						state.Torrent.Id,
+						ids,
+						wanted,
+						state,
+					)
+					window.manager.Draw <- true
+				}()
+
+				// If there's no custom selection, move current cursor down.
+				if len(items) == 1 && len(state.List.Selection) == 0 {
+					state.List.MoveCursor(1)
+				}
+			}
 		case 'o':
 			cursor := state.List.Cursor
 			if cursor >= 0 {
@@ -192,19 +251,43 @@ func (window *TorrentDetailsWindow) OnInput(key tui.Key) {
 				}(path)
 			}
 		}
+	} else if key.ControlCode == tui.ASC_TAB {
+		state.Tab = (state.Tab + 1) % DETAILS_TAB_COUNT
+
+		// Don't wait for the worker to show the new tab's contents.
+		if state.Torrent != nil {
+			id := state.Torrent.Id
+			go func() {
+				getDetails(context.Background(), window.client, id, state)
+				window.manager.Draw <- true
+			}()
+		}
+	} else if key.ControlCode == tui.ASC_ENTER && state.ConfirmFiles {
+		if !state.StartOnConfirm {
+			window.manager.RemoveWindow(window)
+			return
+		}
+		if state.Torrent != nil {
+			err := window.client.UpdateActive([]int{ state.Torrent.Id }, true)
+			if err == nil {
+				window.manager.RemoveWindow(window)
+				return
+			}
+			state.Error = err
+		}
 	} else if key.EscapeSeq != nil {
 		switch *key.EscapeSeq {
 		case tui.ESC_LEFT:
 			window.manager.RemoveWindow(window)
 			return
 		case tui.ESC_DOWN:
-			state.List.MoveCursor(1)
+			current.MoveCursor(1)
 		case tui.ESC_UP:
-			state.List.MoveCursor(-1)
+			current.MoveCursor(-1)
 		case tui.ESC_PGUP:
-			state.List.Page(-1)
+			current.Page(-1)
 		case tui.ESC_PGDOWN:
-			state.List.Page(1)
+			current.Page(1)
 		case tui.ESC_F1:
 			showDetailsCheatsheet(window.window, window.manager)
 		}
@@ -242,7 +325,27 @@ func NewTorrentDetailsWindow(
 		width int,
 		printer func(int, string),
 	) {
-		formatFile(file, width, obfuscated, state.Torrent, printer)
+		var name string
+		if state.Torrent != nil {
+			name = state.Torrent.Name
+		}
+		formatFile(file, width, obfuscated, name, printer)
+	}
+
+	trackerFormatter := func(
+		tracker interface{},
+		width int,
+		printer func(int, string),
+	) {
+		formatTracker(tracker, width, obfuscated, printer)
+	}
+
+	peerFormatter := func(
+		peer interface{},
+		width int,
+		printer func(int, string),
+	) {
+		formatPeer(peer, width, obfuscated, printer)
 	}
 
 	state = &TorrentDetailsState{
@@ -253,6 +356,33 @@ func NewTorrentDetailsWindow(
 			DETAILS_FOOTER_HEIGHT,
 			0,
 			0,
+			false,
+			0,
+			[]int{},
+			0,
+			[]list.Identifiable{},
+		},
+		Trackers: list.List{
+			window,
+			trackerFormatter,
+			DETAILS_HEADER_HEIGHT+2,
+			DETAILS_FOOTER_HEIGHT,
+			0,
+			0,
+			false,
+			0,
+			[]int{},
+			0,
+			[]list.Identifiable{},
+		},
+		Peers: list.List{
+			window,
+			peerFormatter,
+			DETAILS_HEADER_HEIGHT+2,
+			DETAILS_FOOTER_HEIGHT,
+			0,
+			0,
+			false,
 			0,
 			[]int{},
 			0,
@@ -263,8 +393,8 @@ func NewTorrentDetailsWindow(
 	workers := worker.WorkerList{
 		worker.Repeating(
 			3,
-			func() {
-				getDetails(client, id, state)
+			func(ctx context.Context) {
+				getDetails(ctx, client, id, state)
 				manager.Draw <- true
 			},
 		),
@@ -278,20 +408,35 @@ func NewTorrentDetailsWindow(
 		state}
 }
 
+// Details of a paused torrent, for picking its files. Torrent is started once
+// the user is done with the selection, unless it's meant to stay paused.
+func NewTorrentFilesWindow(
+	client *transmission.Client,
+	id int,
+	start bool,
+	obfuscated bool,
+	parent tui.Drawable,
+	manager *WindowManager,
+) *TorrentDetailsWindow {
+	window := NewTorrentDetailsWindow(client, id, obfuscated, parent, manager)
+	window.state.ConfirmFiles, window.state.StartOnConfirm = true, start
+	return window
+}
+
 /* Drawing */
 
 func formatFile(
 	file interface{},
 	width int,
 	obfuscated bool,
-	torrent *transmission.TorrentDetails,
+	torrentName string,
 	printer func(int, string),
 ) {
 	item := file.(transmission.TorrentFile)
 
 	var filename string = item.Name
-	if torrent != nil && strings.HasPrefix(filename, torrent.Name + "/") {
-		filename = strings.TrimPrefix(filename, torrent.Name + "/")
+	if torrentName != "" && strings.HasPrefix(filename, torrentName + "/") {
+		filename = strings.TrimPrefix(filename, torrentName + "/")
 	}
 
 	// Format: # - Done - Priority - Get - Size - Name
@@ -301,7 +446,7 @@ func formatFile(
 	var croppedTitle []rune
 	croppedTitleLength := utils.MinInt(maxTitleLength, len(title))
 	if obfuscated {
-		croppedTitle = []rune(utils.RandomString(croppedTitleLength))
+		croppedTitle = []rune(utils.Obfuscate(filename))[0:croppedTitleLength]
 	} else {
 		croppedTitle = title[0:croppedTitleLength]
 	}
@@ -336,7 +481,7 @@ func drawDetails(window tui.Drawable, state *TorrentDetailsState) {
 			if state.Obfuscated {
 				window.MovePrint(
 					0, 0,
-					utils.RandomString(len([]rune(item.Name))),
+					utils.Obfuscate(item.Name),
 				)
 			} else {
 				window.MovePrint(0, 0, item.Name)
@@ -392,23 +537,74 @@ func drawDetails(window tui.Drawable, state *TorrentDetailsState) {
 			speedString, strings.Repeat(" ", col - len(speedString)),
 		)
 
-		// Separator.
+		// Separator with tabs.
 		window.HLine(4, 0, col)
+		drawDetailsTabs(window, 4, state)
+	}
+
+	switch state.Tab {
+	case DETAILS_TAB_TRACKERS:
+		drawTrackersLegend(window, DETAILS_HEADER_HEIGHT, col)
+	case DETAILS_TAB_PEERS:
+		drawPeersLegend(window, DETAILS_HEADER_HEIGHT, col)
+	default:
+		drawFilesLegend(window, DETAILS_HEADER_HEIGHT, col)
 	}
 
+	// Draw List.
+	current := state.currentList()
+	current.Draw()
+
+	// Draw Error or a reminder of what to do next.
+	switch {
+	case state.Error == nil && state.StartOnConfirm:
+		drawError(window, &Message{ "Choose files to download, then press RETURN to start. q - Keep paused" })
+	case state.Error == nil && state.ConfirmFiles:
+		drawError(window, &Message{ "Choose files to download, then press RETURN. Torrent stays paused" })
+	case state.Error == nil && state.Tab == DETAILS_TAB_TRACKERS && current.Cursor >= 0 && len(current.Items) > 0:
+		tracker := current.Items[current.Cursor].(transmission.TrackerStat)
+		drawError(window, &Message{ trackerResults(tracker) })
+	case state.Error == nil && state.Tab == DETAILS_TAB_PEERS && state.Torrent != nil:
+		drawError(window, &Message{ peersSummary(state.Torrent.PeersFrom, state.PeersOrder) })
+	default:
+		drawError(window, state.Error)
+	}
+}
+
+func drawDetailsTabs(window tui.Drawable, row int, state *TorrentDetailsState) {
+	titles := []string{ "Files", "Trackers", "Peers" }
+	if state.Torrent != nil {
+		counts := []int{ len(state.Torrent.Files), len(state.Torrent.Trackers), len(state.Torrent.Peers) }
+		for tab, count := range counts {
+			if state.loaded[tab] {
+				titles[tab] = fmt.Sprintf("%s (%d)", titles[tab], count)
+			}
+		}
+	}
+
+	x := 2
+	for index, title := range titles {
+		attributes := []tui.Attribute{}
+		if index == state.Tab {
+			attributes = []tui.Attribute{tui.ATTR_REVERSED}
+		}
+
+		label := fmt.Sprintf(" %s ", title)
+		window.WithAttributes(attributes, func() {
+			window.MovePrint(row, x, label)
+		})
+		x += len(label) + 1
+	}
+}
+
+func drawFilesLegend(window tui.Drawable, row int, col int) {
 	// Legend: # - Done - Priority - Get - Size - Name
 	legendFormat := "%3s %-6s %-8s %-3s %-9s %s"
 	window.MovePrintf(
-		DETAILS_HEADER_HEIGHT, 0,
+		row, 0,
 		legendFormat, "#", "Done", "Priority", "Get", "Size", "Name",
 	)
-	window.HLine(DETAILS_HEADER_HEIGHT + 1, 0, col)
-
-	// Draw List.
-	state.List.Draw()
-
-	// Draw Error.
-	drawError(window, state.Error)
+	window.HLine(row + 1, 0, col)
 }
 
 func showDetailsCheatsheet(parent tui.Drawable, manager *WindowManager) {
@@ -425,6 +621,12 @@ func showDetailsCheatsheet(parent tui.Drawable, manager *WindowManager) {
 		HelpItem{ "U", "Set torrent's upload speed limit" },
 		HelpItem{ "m", "Move torrent to a new location" },
 		HelpItem{ "o", "Open the file under cursor with OS's default app" },
+		HelpItem{ "RETURN", "Start the torrent, when adding a magnet link" },
+		HelpItem{ "Tab", "Switch between files, trackers and peers" },
+		HelpItem{ "a", "Add trackers (trackers tab)" },
+		HelpItem{ "e", "Edit announce URL of the tracker (trackers tab)" },
+		HelpItem{ "d", "Remove selected tracker(s) (trackers tab)" },
+		HelpItem{ "s", "Sort by address, download or upload rate (peers tab)" },
 	}
 
 	cheatsheet := NewCheatsheet(parent, items, manager)
@@ -433,19 +635,63 @@ func showDetailsCheatsheet(parent tui.Drawable, manager *WindowManager) {
 
 /* Network */
 
+// Fields of the tab's contents.
+func detailsTabFields(tab int) []string {
+	switch tab {
+	case DETAILS_TAB_TRACKERS:
+		return transmission.DETAILS_TRACKERS_FIELDS
+	case DETAILS_TAB_PEERS:
+		return transmission.DETAILS_PEERS_FIELDS
+	}
+	return transmission.DETAILS_FILES_FIELDS
+}
+
 func getDetails(
+	ctx context.Context,
 	client *transmission.Client,
 	id int,
 	state *TorrentDetailsState,
 ) {
-	torrent, e := client.TorrentDetails(id)
+	tab := state.Tab
+	torrent, e := client.TorrentDetailsContext(ctx, id, detailsTabFields(tab)...)
+
+	// Worker was stopped mid-request, result is irrelevant.
+	if ctx.Err() == context.Canceled {
+		return
+	}
+
+	// Hidden tabs weren't fetched, keep what they had.
+	if previous := state.Torrent; torrent != nil && previous != nil && previous.Id == torrent.Id {
+		if tab != DETAILS_TAB_FILES {
+			torrent.Files = previous.Files
+		}
+		if tab != DETAILS_TAB_TRACKERS {
+			torrent.Trackers = previous.Trackers
+		}
+		if tab != DETAILS_TAB_PEERS {
+			torrent.Peers, torrent.PeersFrom = previous.Peers, previous.PeersFrom
+		}
+	} else {
+		state.loaded = [DETAILS_TAB_COUNT]bool{}
+	}
 
 	state.Error = e
 	state.Torrent = torrent
 	if torrent != nil {
+		state.loaded[tab] = true
 		state.List.Items = transform.GeneralizeFiles(torrent.Files)
+		state.Trackers.SetItems(transform.GeneralizeTrackers(torrent.Trackers))
+		if state.Trackers.Cursor < 0 && len(torrent.Trackers) > 0 {
+			state.Trackers.Cursor = 0
+		}
+		state.Peers.SetItems(sortPeers(torrent.Peers, state.PeersOrder))
+		if state.Peers.Cursor < 0 && len(torrent.Peers) > 0 {
+			state.Peers.Cursor = 0
+		}
 	} else {
 		state.List.Items = []list.Identifiable{}
+		state.Trackers.SetItems([]list.Identifiable{})
+		state.Peers.SetItems([]list.Identifiable{})
 	}
 }
 
@@ -460,7 +706,7 @@ func updatePriority(
 
 	state.Error = e
 	if e == nil {
-		getDetails(client, id, state)
+		getDetails(context.Background(), client, id, state)
 	}
 }
 
@@ -475,7 +721,7 @@ func updateWanted(
 
 	state.Error = e
 	if e == nil {
-		getDetails(client, id, state)
+		getDetails(context.Background(), client, id, state)
 	}
 }
 
@@ -489,7 +735,7 @@ func setDownloadLimit(
 
 	state.Error = e
 	if e == nil {
-		getDetails(client, id, state)
+		getDetails(context.Background(), client, id, state)
 	}
 }
 
@@ -503,7 +749,7 @@ func setUploadLimit(
 
 	state.Error = e
 	if e == nil {
-		getDetails(client, id, state)
+		getDetails(context.Background(), client, id, state)
 	}
 }
 
@@ -517,6 +763,6 @@ func setLocation(
 
 	state.Error = e
 	if e == nil {
-		getDetails(client, id, state)
+		getDetails(context.Background(), client, id, state)
 	}
 }
@@ -5,6 +5,7 @@ import (
 	"strings"
 	"transmission"
 	"transform"
+	"utils"
 )
 
 func formatSize(size int64) string {
@@ -108,6 +109,18 @@ func formatTime(time int32, done bool) string {
 	}
 }
 
+// Progress bar like "[###---]  50%", 'width' characters long.
+func formatProgress(progress float64, width int) string {
+	percent := fmt.Sprintf(" %3.0f%%", progress * 100)
+	barWidth := utils.MaxInt(0, width - len(percent) - 2)
+	filled := utils.MaxInt(0, utils.MinInt(barWidth, int(float64(barWidth) * progress)))
+	return fmt.Sprintf(
+		"[%s%s]%s",
+		strings.Repeat("#", filled),
+		strings.Repeat("-", barWidth - filled),
+		percent)
+}
+
 func idsString(items []transmission.TorrentListItem) string {
 	var idsString string
 	if len(items) == 1 {
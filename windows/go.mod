@@ -8,5 +8,9 @@ require (
     transform v0.0.0
     utils v0.0.0
     suggestions v0.0.0
+    metainfo v0.0.0
+    magnet v0.0.0
+    creator v0.0.0
+    verify v0.0.0
 )
 
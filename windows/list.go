@@ -1,6 +1,7 @@
 package windows
 
 import (
+	"context"
 	"strings"
 	"fmt"
 	"tui"
@@ -9,6 +10,7 @@ import (
 	"list"
 	"worker"
 	"utils"
+	"time"
 )
 
 type Input int
@@ -16,7 +18,7 @@ type Settings *transmission.SessionSettings
 
 const (
 	INFO_HEIGHT = 4
-	HEADER_HEIGHT = 2
+	HEADER_HEIGHT = 3
 	FOOTER_HEIGHT = 2
 )
 
@@ -32,6 +34,9 @@ const (
 	DELETE
 	DELETE_WITH_DATA
 	ADD
+	CREATE
+	VERIFY
+	REPLACE_TRACKERS
 	SELECT
 	CLEAR_SELECT
 	PAUSE
@@ -42,6 +47,7 @@ const (
 	SELECT_ALL
 	INVERT_SELECT
 	OPEN
+	RECONNECT
 	UNKNOWN
 )
 
@@ -64,7 +70,9 @@ type ListWindowState struct {
 	Error error
 	List list.List
 	Settings Settings
-	ConnectionEstablished bool
+	Connection transmission.ConnectionStatus
+	// Nil until fetched for the current connection.
+	Daemon *transmission.Capabilities
 }
 
 type ListWindow struct {
@@ -104,7 +112,7 @@ func (window *ListWindow) OnInput(key tui.Key) {
 		switch command {
 		case STOP_AND_EXIT:
 			var result bool = true
-			if window.client.Connected {
+			if window.client.IsConnected() {
 				op := ExitOperation{}
 				result = handleOperation(window.client, op, window.state)
 			}
@@ -218,9 +226,36 @@ func (window *ListWindow) OnInput(key tui.Key) {
 				window.client,
 				window.window,
 				window.manager,
+				window.obfuscated,
 				func(err error) { drawError(window.window, err) },
 			)
 			window.manager.AddWindow(dialog)
+		case CREATE:
+			// Open create torrent dialog.
+			window.state.PendingOperation = nil
+			dialog := NewCreateTorrentWindow(window.client, window.window, window.manager)
+			window.manager.AddWindow(dialog)
+		case VERIFY:
+			// Open local data verification dialog.
+			window.state.PendingOperation = nil
+			dialog := NewVerifyWindow(window.window, window.manager, window.obfuscated)
+			window.manager.AddWindow(dialog)
+		case REPLACE_TRACKERS:
+			// Open tracker URL replacement dialog for selected torrents, or
+			// all of them.
+			window.state.PendingOperation = nil
+			var ids []int
+			if len(window.state.List.Selection) > 0 {
+				ids = append([]int{}, window.state.List.Selection...)
+			}
+			dialog := NewReplaceTrackersWindow(
+				window.client,
+				ids,
+				window.obfuscated,
+				window.window,
+				window.manager,
+			)
+			window.manager.AddWindow(dialog)
 		case DETAILS:
 			// Go to torrent details.
 			if window.state.List.Cursor >= 0 {
@@ -235,6 +270,14 @@ func (window *ListWindow) OnInput(key tui.Key) {
 				)
 				window.manager.AddWindow(details)
 			}
+		case RECONNECT:
+			// Skip the rest of reconnect delay. Refresh in the background, the
+			// daemon might still be unreachable.
+			window.client.Reconnect()
+			go func() {
+				updateList(context.Background(), window.client, window.state)
+				window.manager.Draw <- true
+			}()
 		case OPEN:
 			// Open torrent location.
 			if window.state.List.Cursor >= 0 {
@@ -288,20 +331,21 @@ func NewListWindow(
 			FOOTER_HEIGHT,
 			0,
 			0,
+			false,
 			0,
 			[]int{},
 			0,
 			[]list.Identifiable{}}}
 
 	// Handle list update.
-	listWorker := worker.Repeating(3, func() {
-		updateList(client, state)
+	listWorker := worker.Repeating(3, func(ctx context.Context) {
+		updateList(ctx, client, state)
 		manager.Draw <- true
 	})
 
 	// Handle session update.
-	sessionWorker := worker.Repeating(3, func() {
-		updateSession(client, state)
+	sessionWorker := worker.Repeating(3, func(ctx context.Context) {
+		updateSession(ctx, client, state)
 		manager.Draw <- true
 	})
 
@@ -333,7 +377,7 @@ func formatTorrentListItem(
 	var croppedTitle []rune
 	croppedTitleLength := len(title)
 	if (obfuscated) {
-		croppedTitle = []rune(utils.RandomString(croppedTitleLength))
+		croppedTitle = []rune(utils.Obfuscate(item.Name))
 	} else {
 		croppedTitle = title
 	}
@@ -383,9 +427,12 @@ func drawList(window tui.Drawable, state ListWindowState) {
 		legendUp = legendUp +	 " *"
 	}
 
+	// Connection status.
+	drawConnectionStatus(window, state.Connection, state.Daemon, col)
+
 	legendFormat := fmt.Sprintf("%%5s %%-%ds %%-6s %%-7s %%-9s %%-12s %%-6s %%-9s %%-9s", maxTitleLength)
 	window.MovePrintf(
-		0,
+		1,
 		0,
 		legendFormat,
 		"Id",
@@ -398,7 +445,7 @@ func drawList(window tui.Drawable, state ListWindowState) {
 		legendDown,
 		legendUp,
 	)
-	window.HLine(1, 0, col)
+	window.HLine(2, 0, col)
 
 	// List.
 	state.List.Draw()
@@ -429,6 +476,46 @@ func drawList(window tui.Drawable, state ListWindowState) {
 	window.Redraw()
 }
 
+func drawConnectionStatus(
+	window tui.Drawable,
+	status transmission.ConnectionStatus,
+	daemon *transmission.Capabilities,
+	width int,
+) {
+	var text string
+	switch status.State {
+	case transmission.CONNECTION_CONNECTING:
+		text = "Connecting..."
+	case transmission.CONNECTION_CONNECTED:
+		if daemon == nil {
+			text = "Connected"
+		} else if !daemon.Compatible() {
+			text = fmt.Sprintf(
+				"Connected to %s. Daemon requires RPC version %d or newer, some actions may fail",
+				daemon, daemon.RPCVersionMinimum)
+		} else {
+			text = fmt.Sprintf("Connected to %s", daemon)
+		}
+	case transmission.CONNECTION_RECONNECTING:
+		text = fmt.Sprintf("Reconnecting (attempt %d)...", status.Attempts + 1)
+	case transmission.CONNECTION_FAILED:
+		wait := time.Until(status.NextRetry).Round(time.Second)
+		if wait > 0 {
+			text = fmt.Sprintf("Offline, retrying in %s (attempt %d). Press 'r' to retry now", wait, status.Attempts + 1)
+		} else {
+			text = fmt.Sprintf("Offline, retrying (attempt %d)...", status.Attempts + 1)
+		}
+	}
+
+	if status.IsOnline() {
+		window.MovePrint(0, 0, text)
+	} else {
+		window.WithAttribute(tui.ATTR_BOLD, func() {
+			window.MovePrint(0, 0, text)
+		})
+	}
+}
+
 func drawError(window tui.Drawable, err error) {
 	row, col := window.MaxYX()
 
@@ -444,18 +531,35 @@ func drawError(window tui.Drawable, err error) {
 
 /* Network */
 
-func updateList(client *transmission.Client, state *ListWindowState) {
-	list, err := client.List()
+func updateList(ctx context.Context, client *transmission.Client, state *ListWindowState) {
+	list, err := client.ListContext(ctx)
+
+	// Worker was stopped mid-request, result is irrelevant.
+	if ctx.Err() == context.Canceled {
+		return
+	}
 
+	// Last known list stays on screen while offline, greyed out.
 	if list != nil {
 		state.List.Items = transform.GeneralizeTorrents(*list, true)
 	}
 
+	state.Connection = client.Status()
+	state.List.Dimmed = !state.Connection.IsOnline()
 	state.Error = err
 }
 
-func updateSession(client *transmission.Client, state *ListWindowState) {
-	settings, err := client.GetSessionSettings()
+func updateSession(ctx context.Context, client *transmission.Client, state *ListWindowState) {
+	daemon, _ := client.CapabilitiesContext(ctx)
+	settings, err := client.GetSessionSettingsContext(ctx)
+
+	if ctx.Err() == context.Canceled {
+		return
+	}
+
+	// Nil when offline, so a different daemon after reconnect isn't mistaken
+	// for the old one.
+	state.Daemon = daemon
 
 	if settings != nil {
 		state.Settings = settings
@@ -472,7 +576,7 @@ func setGlobalDownloadLimit(client *transmission.Client, limit int, state *ListW
 	if e != nil {
 		state.Error = e
 	} else {
-		updateSession(client, state)
+		updateSession(context.Background(), client, state)
 	}
 }
 
@@ -482,7 +586,7 @@ func setGlobalUploadLimit(client *transmission.Client, limit int, state *ListWin
 	if e != nil {
 		state.Error = e
 	} else {
-		updateSession(client, state)
+		updateSession(context.Background(), client, state)
 	}
 }
 
@@ -496,7 +600,7 @@ func setListLocation(client *transmission.Client, ids []int, location string, st
 	if e != nil {
 		state.Error = e
 	} else {
-		updateList(client, state)
+		updateList(context.Background(), client, state)
 	}
 }
 
@@ -528,7 +632,7 @@ func handleOperation(client *transmission.Client, operation interface{}, state *
 	if e != nil {
 		state.Error = e
 	} else {
-		updateList(client, state)
+		updateList(context.Background(), client, state)
 	}
 
 	return e == nil
@@ -544,6 +648,9 @@ func showListCheatsheet(parent tui.Drawable, manager *WindowManager) {
 		HelpItem{ "jk↑↓", "Move cursor up and down" },
 		HelpItem{ "l→", "Go to torrent details" },
 		HelpItem{ "a", "Add new torrent" },
+		HelpItem{ "n", "Create a new torrent from local files" },
+		HelpItem{ "v", "Verify local data against a .torrent file" },
+		HelpItem{ "T", "Replace tracker URLs in selected or all torrents" },
 		HelpItem{ "Space", "Toggle selection" },
 		HelpItem{ "c", "Clear selection" },
 		HelpItem{ "A", "Select all items" },
@@ -555,6 +662,7 @@ func showListCheatsheet(parent tui.Drawable, manager *WindowManager) {
 		HelpItem{ "U", "Set global upload speed limit" },
 		HelpItem{ "m", "Move selected torrent(s) to a new location" },
 		HelpItem{ "o", "Open the torrent using OS's default app" },
+		HelpItem{ "r", "Reconnect to the daemon now" },
 	}
 
 	cheatsheet := NewCheatsheet(parent, items, manager)
@@ -576,6 +684,12 @@ func control(char tui.Key) Input {
 			return DELETE_WITH_DATA
 		case 'a':
 			return ADD
+		case 'n':
+			return CREATE
+		case 'v':
+			return VERIFY
+		case 'T':
+			return REPLACE_TRACKERS
 		case ' ':
 			return SELECT
 		case 'c':
@@ -600,6 +714,8 @@ func control(char tui.Key) Input {
 			return INVERT_SELECT
 		case 'o':
 			return OPEN
+		case 'r':
+			return RECONNECT
 		}
 	} else if char.EscapeSeq != nil {
 		switch *char.EscapeSeq {
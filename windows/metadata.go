@@ -0,0 +1,253 @@
+package windows
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+	"transmission"
+	"tui"
+	"utils"
+	"worker"
+)
+
+/* Data */
+
+const (
+	METADATA_CONTROLS_TEXT = "ESC - Close, torrent stays paused"
+	// No metadata progress for this long means no peer is sending it.
+	METADATA_STALL_SECONDS = 60
+)
+
+type MetadataState struct {
+	Name string
+	Percent float64
+	Ready bool
+	Error error
+	// Torrent was started to fetch the metadata.
+	Started bool
+	// Last time the percentage moved.
+	Progressed time.Time
+
+	// Guards Ready, set by the worker and read by the UI loop. Once it's set,
+	// the worker must not touch the torrent anymore.
+	lock sync.Mutex
+	// File selection was shown.
+	handedOver bool
+}
+
+func (state *MetadataState) isReady() bool {
+	state.lock.Lock()
+	defer state.lock.Unlock()
+	return state.Ready
+}
+
+// Whether the file selection should be shown now. True only once.
+func (state *MetadataState) handOver() bool {
+	state.lock.Lock()
+	defer state.lock.Unlock()
+
+	if !state.Ready || state.handedOver {
+		return false
+	}
+	state.handedOver = true
+	return true
+}
+
+/* Window */
+
+// Waits for a magnet link's metadata, then hands over to the file selection.
+type MetadataWindow struct {
+	parent tui.Drawable
+	window tui.Drawable
+	manager *WindowManager
+	workers worker.WorkerList
+	client *transmission.Client
+	id int
+	obfuscated bool
+	state *MetadataState
+	onReady func()
+}
+
+func (window *MetadataWindow) IsFullScreen() bool {
+	return false
+}
+
+func (window *MetadataWindow) SetActive(active bool) {
+	if active {
+		tui.HideCursor()
+		window.workers.Start()
+	} else {
+		window.workers.Stop()
+	}
+}
+
+func (window *MetadataWindow) OnInput(key tui.Key) {
+	if key.ControlCode == tui.ASC_ESC {
+		window.manager.RemoveWindow(window)
+
+		// Fetching is abandoned, the torrent goes back to being paused.
+		if window.state.Started {
+			go func() {
+				window.client.UpdateActive([]int{ window.id }, false)
+			}()
+		}
+	}
+}
+
+func (window *MetadataWindow) Draw() {
+	state := window.state
+
+	// Window changes have to happen on the UI loop, and drawing is the first
+	// chance after the worker noticed the metadata.
+	if state.handOver() {
+		window.manager.RemoveWindow(window)
+		window.onReady()
+		return
+	}
+
+	window.window.Box()
+
+	_, col := window.window.MaxYX()
+	startX, width := 2, col-4
+
+	// Name.
+	name := state.Name
+	if window.obfuscated {
+		name = utils.Obfuscate(name)
+	}
+	window.window.MovePrint(1, startX, cropRunes(fmt.Sprintf("Fetching metadata: %s", name), width))
+
+	// Progress.
+	if state.Error != nil {
+		window.window.MovePrint(2, startX, cropRunes(fmt.Sprintf("%s", state.Error), width))
+	} else if state.Started && time.Since(state.Progressed) >= METADATA_STALL_SECONDS * time.Second {
+		stalled := fmt.Sprintf(
+			"No metadata for %s, peers may be unreachable",
+			formatTime(int32(time.Since(state.Progressed).Seconds()), false))
+		window.window.MovePrint(2, startX, cropRunes(stalled, width))
+	} else {
+		window.window.MovePrint(2, startX, formatProgress(state.Percent, width))
+	}
+
+	// Delimiter.
+	window.window.HLine(3, 1, col-2)
+
+	// Controls reminder.
+	window.window.MovePrint(4, startX + (width - len(METADATA_CONTROLS_TEXT)) / 2, METADATA_CONTROLS_TEXT)
+
+	window.window.Redraw()
+}
+
+func (window *MetadataWindow) Resize() {
+	height, width, y, x := MeasureMetadataWindow(window.parent)
+	window.window.Move(y, x)
+	window.window.Resize(height, width)
+}
+
+func MeasureMetadataWindow(parent tui.Drawable) (int, int, int, int) {
+	rows, cols := parent.MaxYX()
+
+	height, width := 6, utils.MinInt(cols, utils.MaxInt(60, cols / 2))
+	y, x := (rows - height) / 2, (cols - width) / 2
+	return height, width, y, x
+}
+
+func NewMetadataWindow(
+	client *transmission.Client,
+	torrent transmission.TorrentAddedInfo,
+	obfuscated bool,
+	parent tui.Drawable,
+	manager *WindowManager,
+	onReady func(),
+) *MetadataWindow {
+	height, width, y, x := MeasureMetadataWindow(parent)
+	window := parent.Sub(y, x, height, width)
+
+	state := &MetadataState{ Name: torrent.Name }
+
+	workers := worker.WorkerList{
+		worker.Repeating(
+			1,
+			func(ctx context.Context) {
+				getMetadataProgress(ctx, client, torrent.Id, state)
+				manager.Draw <- true
+			},
+		),
+	}
+
+	return &MetadataWindow{
+		parent,
+		window,
+		manager,
+		workers,
+		client,
+		torrent.Id,
+		obfuscated,
+		state,
+		onReady}
+}
+
+/* Network */
+
+func getMetadataProgress(
+	ctx context.Context,
+	client *transmission.Client,
+	id int,
+	state *MetadataState,
+) {
+	// Torrent is stopped and waits for the file selection, which might not be
+	// shown yet. Starting it again would download pieces.
+	if state.isReady() {
+		return
+	}
+
+	// Magnets are added paused so nothing is downloaded before the files are
+	// picked, but metadata only comes from peers of a running torrent.
+	if !state.Started {
+		if err := client.UpdateActiveContext(ctx, []int{ id }, true); err != nil {
+			if ctx.Err() != context.Canceled {
+				state.Error = err
+			}
+			return
+		}
+		state.Started, state.Progressed = true, time.Now()
+	}
+
+	torrents, err := client.TorrentGetContext(ctx, []int{ id }, "id", "name", "metadataPercentComplete", "files")
+
+	// Worker was stopped mid-request, result is irrelevant.
+	if ctx.Err() == context.Canceled {
+		return
+	}
+
+	if err != nil {
+		state.Error = err
+		return
+	}
+
+	if len(torrents) == 0 {
+		state.Error = &Message{ "Torrent was removed" }
+		return
+	}
+
+	torrent := torrents[0]
+	state.Error = nil
+	state.Name = torrent.Name
+	if torrent.MetadataPercentComplete != state.Percent {
+		state.Percent, state.Progressed = torrent.MetadataPercentComplete, time.Now()
+	}
+
+	// Stop before pieces are requested, the file selection starts it again.
+	if len(torrent.Files) > 0 {
+		if err := client.UpdateActiveContext(ctx, []int{ id }, false); err != nil {
+			if ctx.Err() != context.Canceled {
+				state.Error = err
+			}
+			return
+		}
+		state.lock.Lock()
+		state.Started, state.Ready = false, true
+		state.lock.Unlock()
+	}
+}
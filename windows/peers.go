@@ -0,0 +1,128 @@
+package windows
+
+import (
+	"fmt"
+	"list"
+	"net"
+	"sort"
+	"strings"
+	"transform"
+	"transmission"
+	"tui"
+	"utils"
+)
+
+const (
+	PEERS_ORDER_ADDRESS int = 0
+	PEERS_ORDER_DOWNLOAD = 1
+	PEERS_ORDER_UPLOAD = 2
+	PEERS_ORDER_COUNT = 3
+)
+
+/* Drawing */
+
+func formatPeer(
+	item interface{},
+	width int,
+	obfuscated bool,
+	printer func(int, string),
+) {
+	peer := item.(transmission.Peer)
+
+	// IPv6 addresses go in brackets.
+	address := net.JoinHostPort(peer.Address, fmt.Sprintf("%d", peer.Port))
+	if obfuscated {
+		address = utils.Obfuscate(address)
+	}
+
+	encrypted := "-"
+	if peer.IsEncrypted {
+		encrypted = "Yes"
+	}
+
+	connection := "TCP"
+	if peer.IsUTP {
+		connection = "uTP"
+	}
+	if peer.IsIncoming {
+		connection += " in"
+	} else {
+		connection += " out"
+	}
+
+	// Format: Address - Flags - Done - Down - Up - Encrypted - Connection - Client
+	prefix := fmt.Sprintf(
+		"%-26s %-9s %4s %9s %9s %-3s %-7s ",
+		cropRunes(address, 26),
+		cropRunes(peer.FlagStr, 9),
+		fmt.Sprintf("%3.0f%%", peer.Progress * 100),
+		formatSpeed(float32(peer.RateToClient)),
+		formatSpeed(float32(peer.RateToPeer)),
+		encrypted,
+		connection,
+	)
+
+	clientLength := utils.MaxInt(0, width - len(prefix))
+	client := cropRunes(peer.ClientName, clientLength)
+	spacesLength := clientLength - len([]rune(client))
+	printer(0, prefix + client + strings.Repeat(" ", spacesLength))
+}
+
+func drawPeersLegend(window tui.Drawable, row int, col int) {
+	// Legend: Address - Flags - Done - Down - Up - Encrypted - Connection - Client
+	legendFormat := "%-26s %-9s %4s %9s %9s %-3s %-7s %s"
+	window.MovePrintf(
+		row, 0,
+		legendFormat, "Address", "Flags", "Done", "Down", "Up", "Enc", "Via", "Client",
+	)
+	window.HLine(row + 1, 0, col)
+}
+
+// Where connected peers came from, and how they're sorted.
+func peersSummary(from transmission.PeersFrom, order int) string {
+	orders := []string{ "address", "download rate", "upload rate" }
+	return fmt.Sprintf(
+		"From: tracker %d, DHT %d, PEX %d, LPD %d, incoming %d, cache %d, LTEP %d | Sorted by %s",
+		from.FromTracker,
+		from.FromDht,
+		from.FromPex,
+		from.FromLpd,
+		from.FromIncoming,
+		from.FromCache,
+		from.FromLtep,
+		orders[order],
+	)
+}
+
+/* Sorting */
+
+// Fastest peers first when sorting by rate, ties are broken by address.
+func sortPeers(peers []transmission.Peer, order int) []list.Identifiable {
+	sorted := append([]transmission.Peer{}, peers...)
+	sort.SliceStable(sorted, func(l, r int) bool {
+		left, right := sorted[l], sorted[r]
+		switch {
+		case order == PEERS_ORDER_DOWNLOAD && left.RateToClient != right.RateToClient:
+			return left.RateToClient > right.RateToClient
+		case order == PEERS_ORDER_UPLOAD && left.RateToPeer != right.RateToPeer:
+			return left.RateToPeer > right.RateToPeer
+		case left.Address != right.Address:
+			return left.Address < right.Address
+		default:
+			return left.Port < right.Port
+		}
+	})
+	return transform.GeneralizePeers(sorted)
+}
+
+// Switches to the next sort order, keeping the cursor at the top.
+func changePeersOrder(state *TorrentDetailsState) {
+	state.PeersOrder = (state.PeersOrder + 1) % PEERS_ORDER_COUNT
+	if state.Torrent != nil {
+		state.Peers.SetItems(sortPeers(state.Torrent.Peers, state.PeersOrder))
+	}
+	state.Peers.Cursor, state.Peers.Offset = 0, 0
+	if len(state.Peers.Items) == 0 {
+		state.Peers.Cursor = -1
+	}
+}
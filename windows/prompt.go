@@ -238,3 +238,30 @@ func PathPrompt(
 		suggestions.GetSuggestedDirs)
 	manager.AddWindow(prompt)
 }
+
+func TextPrompt(
+	parent tui.Drawable,
+	manager *WindowManager,
+	title string,
+	initial string,
+	onFinish func(string),
+) {
+	var prompt *Prompt
+	prompt = NewPrompt(
+		parent,
+		manager,
+		title,
+		0,
+		"",
+		initial,
+		true,
+		func(output string) {
+			manager.RemoveWindow(prompt)
+			onFinish(output)
+		},
+		func() {
+			manager.RemoveWindow(prompt)
+		},
+		nil)
+	manager.AddWindow(prompt)
+}
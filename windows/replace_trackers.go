@@ -0,0 +1,743 @@
+package windows
+
+import (
+	"context"
+	"fmt"
+	"list"
+	"regexp"
+	"strings"
+	"transmission"
+	"tui"
+	"utils"
+)
+
+const (
+	REPLACE_FOCUS_FIND int = 0
+	REPLACE_FOCUS_REPLACE = 1
+	REPLACE_FOCUS_REGEX = 2
+	REPLACE_FOCUS_CONFIRM = 3
+	REPLACE_FOCUS_CANCEL = 4
+	REPLACE_FOCUS_COUNT = 5
+)
+
+const (
+	CHANGE_PENDING = "Pending"
+	CHANGE_OK = "OK"
+	CHANGE_FAILED = "Failed"
+)
+
+const TRACKER_CHANGES_HEADER_HEIGHT = 4
+const TRACKER_CHANGES_CONTROLS_TEXT = "RETURN - Apply changes | q - Back | F1 - Help"
+
+type ReplaceTrackersState struct {
+	FindField *InputField
+	ReplaceField *InputField
+	Regex bool
+	Focus int
+
+	// Torrents to look through, nil means all of them.
+	Ids []int
+	Error error
+
+	// Trackers are being fetched in the background.
+	Loading bool
+	cancel context.CancelFunc
+	// Fetched changes, waiting for the UI loop to show them.
+	changes []TrackerChange
+}
+
+/* Dialog */
+
+// Asks for a pattern to look for in announce URLs of many torrents at once,
+// and what to replace it with. Changes are previewed before anything is sent.
+type ReplaceTrackersWindow struct {
+	client *transmission.Client
+	parent tui.Drawable
+	window tui.Drawable
+	manager *WindowManager
+	obfuscated bool
+	state *ReplaceTrackersState
+}
+
+func (window *ReplaceTrackersWindow) IsFullScreen() bool {
+	return false
+}
+
+func (window *ReplaceTrackersWindow) SetActive(active bool) {
+	field := window.focusedField()
+	if field == nil {
+		tui.HideCursor()
+		return
+	}
+
+	if active {
+		field.IsActive = true
+		window.manager.AddInputReader(field)
+	} else {
+		window.manager.RemoveInputReader(field)
+		tui.HideCursor()
+	}
+}
+
+func (window *ReplaceTrackersWindow) focusedField() *InputField {
+	switch window.state.Focus {
+	case REPLACE_FOCUS_FIND:
+		return window.state.FindField
+	case REPLACE_FOCUS_REPLACE:
+		return window.state.ReplaceField
+	}
+	return nil
+}
+
+func (dialog *ReplaceTrackersWindow) Draw() {
+	window, state := dialog.window, dialog.state
+
+	// Window changes have to happen on the UI loop, and drawing is the first
+	// chance after the fetch is done.
+	if state.changes != nil {
+		changes := state.changes
+		state.changes = nil
+		dialog.manager.RemoveWindow(dialog)
+		preview := NewTrackerChangesWindow(dialog.client, changes, dialog.obfuscated, dialog.parent, dialog.manager)
+		dialog.manager.AddWindow(preview)
+		return
+	}
+
+	window.Box()
+
+	_, col := window.MaxYX()
+	startX, width := 2, col-4
+
+	// Header
+	window.MovePrint(1, startX, "Replace tracker URLs")
+	window.HLine(2, 1, col-2)
+
+	// Fields
+	window.MovePrint(3, startX, "Find in announce URLs:")
+	state.FindField.Draw()
+	window.MovePrint(5, startX, "Replace with:")
+	state.ReplaceField.Draw()
+
+	// Options
+	checkbox := "[ ]"
+	if state.Regex {
+		checkbox = "[x]"
+	}
+	drawFocusable(window, state.Focus == REPLACE_FOCUS_REGEX, 7, startX, fmt.Sprintf("%s Regular expression", checkbox))
+
+	scope := "In: all torrents"
+	if state.Ids != nil {
+		scope = fmt.Sprintf("In: %d selected torrent(s)", len(state.Ids))
+	}
+	window.MovePrint(8, startX, scope)
+	window.HLine(9, 1, col-2)
+
+	// Status
+	switch {
+	case state.Loading:
+		window.MovePrint(10, startX, cropRunes("Loading trackers...", width))
+	case state.Error != nil:
+		window.MovePrint(10, startX, cropRunes(fmt.Sprintf("%s", state.Error), width))
+	case state.Regex:
+		window.WithAttribute(tui.ATTR_DIM, func() {
+			window.MovePrint(10, startX, cropRunes("Go regexp syntax, $1 or ${name} in replacement refer to groups", width))
+		})
+	default:
+		window.WithAttribute(tui.ATTR_DIM, func() {
+			window.MovePrint(10, startX, cropRunes("Every occurrence of the text is replaced", width))
+		})
+	}
+
+	// Buttons
+	window.HLine(11, 1, col-2)
+
+	confirm, cancel := "Preview", "Cancel"
+	buttonWidth := width / 2
+	drawFocusable(
+		window,
+		state.Focus == REPLACE_FOCUS_CONFIRM,
+		12, startX + (buttonWidth - len(confirm)) / 2,
+		confirm)
+	drawFocusable(
+		window,
+		state.Focus == REPLACE_FOCUS_CANCEL,
+		12, startX + buttonWidth + (buttonWidth - len(cancel)) / 2,
+		cancel)
+
+	// Enable cursor on input fields.
+	field := dialog.focusedField()
+	if field != nil {
+		tui.ShowCursor()
+	} else {
+		tui.HideCursor()
+	}
+
+	window.Redraw()
+
+	if field != nil {
+		field.SetCursor(window)
+	}
+}
+
+func (window *ReplaceTrackersWindow) Resize() {
+	height, width, y, x := MeasureReplaceTrackersWindow(window.parent)
+	window.state.FindField.Length = width - 4
+	window.state.ReplaceField.Length = width - 4
+	window.window.Move(y, x)
+	window.window.Resize(height, width)
+}
+
+func MeasureReplaceTrackersWindow(parent tui.Drawable) (int, int, int, int) {
+	rows, cols := parent.MaxYX()
+
+	height, width := 14, utils.MinInt(cols, utils.MaxInt(60, cols * 3 / 4))
+	y, x := (rows - height) / 2, (cols - width) / 2
+	return height, width, y, x
+}
+
+func (window *ReplaceTrackersWindow) OnInput(key tui.Key) {
+	state := window.state
+
+	// Only cancelling is possible while loading.
+	if state.Loading {
+		if key.ControlCode == tui.ASC_ESC {
+			window.close()
+		}
+		return
+	}
+
+	if key.ControlCode != 0 {
+		switch key.ControlCode {
+		case tui.ASC_TAB:
+			window.UpdateFocus(nil, 1)
+		case tui.ASC_ESC:
+			window.close()
+		case tui.ASC_ENTER:
+			if state.Focus == REPLACE_FOCUS_CANCEL {
+				window.close()
+			} else {
+				window.preview()
+			}
+		}
+	} else if key.Rune != nil && *key.Rune == ' ' {
+		if state.Focus == REPLACE_FOCUS_REGEX {
+			state.Regex = !state.Regex
+			state.Error = nil
+			window.redraw()
+		}
+	} else if key.EscapeSeq != nil {
+		switch *key.EscapeSeq {
+		case tui.ESC_LEFT, tui.ESC_UP:
+			window.UpdateFocus(nil, -1)
+		case tui.ESC_RIGHT, tui.ESC_DOWN:
+			window.UpdateFocus(nil, 1)
+		}
+	}
+}
+
+// Closing stops fetching trackers.
+func (window *ReplaceTrackersWindow) close() {
+	if window.state.cancel != nil {
+		window.state.cancel()
+	}
+	window.manager.RemoveWindow(window)
+}
+
+// Looks up matching trackers in the background and shows what would change.
+// Going through thousands of torrents takes a while.
+func (window *ReplaceTrackersWindow) preview() {
+	state := window.state
+
+	find := string(state.FindField.Value)
+	if find == "" {
+		state.Error = &Message{ "Enter the text to look for" }
+		window.redraw()
+		return
+	}
+
+	pattern, err := compileTrackerPattern(find, state.Regex)
+	if err != nil {
+		state.Error = err
+		window.redraw()
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	state.cancel = cancel
+	state.Loading, state.Error = true, nil
+
+	replacement, isRegex := string(state.ReplaceField.Value), state.Regex
+	go func() {
+		defer cancel()
+
+		torrents, err := window.client.TorrentGetContext(ctx, state.Ids, "id", "name", "trackers")
+		if ctx.Err() != nil {
+			// Cancelled, dialog is gone.
+			return
+		}
+
+		switch {
+		case err != nil:
+			state.Error = err
+		default:
+			changes := planTrackerChanges(torrents, pattern, replacement, isRegex)
+			if len(changes) == 0 {
+				state.Error = &Message{ "No announce URLs would change" }
+			} else {
+				state.changes = changes
+			}
+		}
+		state.Loading = false
+		window.manager.Draw <- true
+	}()
+
+	window.redraw()
+}
+
+func (window *ReplaceTrackersWindow) redraw() {
+	go func() {
+		window.manager.Draw <- true
+	}()
+}
+
+func (window *ReplaceTrackersWindow) HandleInputFieldUpdate(field *InputField, result InputFieldResult) {
+	switch result {
+	case FOCUS_FORWARD:
+		window.UpdateFocus(field, 1)
+	case FOCUS_BACKWARD:
+		window.UpdateFocus(field, -1)
+	case UPDATE:
+		window.state.Error = nil
+		window.redraw()
+	}
+}
+
+func (window *ReplaceTrackersWindow) UpdateFocus(source *InputField, direction int) {
+	if source != nil {
+		source.IsActive = false
+		window.manager.RemoveInputReader(source)
+	}
+
+	window.state.Focus = (window.state.Focus + direction + REPLACE_FOCUS_COUNT) % REPLACE_FOCUS_COUNT
+
+	if newInput := window.focusedField(); newInput != nil {
+		newInput.IsActive = true
+		window.manager.AddInputReader(newInput)
+	}
+
+	window.redraw()
+}
+
+// Nil ids mean all torrents.
+func NewReplaceTrackersWindow(
+	client *transmission.Client,
+	ids []int,
+	obfuscated bool,
+	parent tui.Drawable,
+	manager *WindowManager,
+) *ReplaceTrackersWindow {
+	height, width, y, x := MeasureReplaceTrackersWindow(parent)
+	window := parent.Sub(y, x, height, width)
+
+	field := func(y int) *InputField {
+		return &InputField{
+			X: 2, Y: y, Length: width - 4,
+			IsModal: false,
+			EnterToConfirm: false,
+			IsActive: false,
+			Value: []rune{},
+			Charset: "",
+			Suggester: nil,
+			Suggestion: nil,
+			Manager: manager,
+			Parent: window,
+			OnResult: nil,
+		}
+	}
+
+	state := &ReplaceTrackersState{
+		FindField: field(4),
+		ReplaceField: field(6),
+		Ids: ids,
+	}
+
+	dialog := &ReplaceTrackersWindow{
+		client,
+		parent,
+		window,
+		manager,
+		obfuscated,
+		state}
+
+	state.FindField.OnResult = dialog.HandleInputFieldUpdate
+	state.ReplaceField.OnResult = dialog.HandleInputFieldUpdate
+
+	return dialog
+}
+
+/* Changes */
+
+// One announce URL to replace, and how it went.
+type TrackerChange struct {
+	Index int
+	TorrentId int
+	TorrentName string
+	TrackerId int
+	Old string
+	New string
+	Status string
+	Error error
+}
+
+func (change TrackerChange) Id() int {
+	return change.Index
+}
+
+// Plain text is matched literally.
+func compileTrackerPattern(find string, isRegex bool) (*regexp.Regexp, error) {
+	if !isRegex {
+		find = regexp.QuoteMeta(find)
+	}
+
+	pattern, err := regexp.Compile(find)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid pattern: %s", err)
+	}
+	return pattern, nil
+}
+
+// Every announce URL the replacement would change. URLs that stay the same
+// or become empty are left alone.
+func planTrackerChanges(
+	torrents []transmission.Torrent,
+	pattern *regexp.Regexp,
+	replacement string,
+	isRegex bool,
+) []TrackerChange {
+	changes := []TrackerChange{}
+	for _, torrent := range torrents {
+		for _, tracker := range torrent.Trackers {
+			var url string
+			if isRegex {
+				url = pattern.ReplaceAllString(tracker.Announce, replacement)
+			} else {
+				url = pattern.ReplaceAllLiteralString(tracker.Announce, replacement)
+			}
+
+			url = strings.TrimSpace(url)
+			if url == "" || url == tracker.Announce {
+				continue
+			}
+
+			changes = append(changes, TrackerChange{
+				len(changes),
+				torrent.Id,
+				torrent.Name,
+				tracker.Id,
+				tracker.Announce,
+				url,
+				CHANGE_PENDING,
+				nil})
+		}
+	}
+	return changes
+}
+
+/* Preview */
+
+type TrackerChangesState struct {
+	List list.List
+	Obfuscated bool
+
+	// Nothing can be applied twice.
+	Applying bool
+	Applied bool
+	cancel context.CancelFunc
+	Error error
+}
+
+// Dry run of the replacement. Changes are sent once confirmed, one request
+// per torrent, and each row shows how it went.
+type TrackerChangesWindow struct {
+	client *transmission.Client
+	window tui.Drawable
+	manager *WindowManager
+	state *TrackerChangesState
+}
+
+func (window *TrackerChangesWindow) IsFullScreen() bool {
+	return true
+}
+
+func (window *TrackerChangesWindow) SetActive(active bool) {
+	tui.HideCursor()
+}
+
+func (window *TrackerChangesWindow) OnInput(key tui.Key) {
+	state := window.state
+
+	if key.Rune != nil {
+		switch *key.Rune {
+		case 'q', 'h':
+			window.close()
+			return
+		case 'j':
+			state.List.MoveCursor(1)
+		case 'k':
+			state.List.MoveCursor(-1)
+		}
+	} else if key.ControlCode != 0 {
+		switch key.ControlCode {
+		case tui.ASC_ENTER:
+			if !state.Applying && !state.Applied {
+				window.apply()
+			}
+		case tui.ASC_ESC:
+			window.close()
+			return
+		}
+	} else if key.EscapeSeq != nil {
+		switch *key.EscapeSeq {
+		case tui.ESC_LEFT:
+			window.close()
+			return
+		case tui.ESC_DOWN:
+			state.List.MoveCursor(1)
+		case tui.ESC_UP:
+			state.List.MoveCursor(-1)
+		case tui.ESC_PGUP:
+			state.List.Page(-1)
+		case tui.ESC_PGDOWN:
+			state.List.Page(1)
+		case tui.ESC_F1:
+			showTrackerChangesCheatsheet(window.window, window.manager)
+		}
+	}
+
+	go func() {
+		window.manager.Draw <- true
+	}()
+}
+
+// Leaving stops sending changes to torrents that weren't reached yet.
+func (window *TrackerChangesWindow) close() {
+	if window.state.cancel != nil {
+		window.state.cancel()
+	}
+	window.manager.RemoveWindow(window)
+}
+
+// Sends changes torrent by torrent in the background, redrawing after each.
+func (window *TrackerChangesWindow) apply() {
+	state := window.state
+
+	ctx, cancel := context.WithCancel(context.Background())
+	state.cancel = cancel
+	state.Applying, state.Error = true, nil
+
+	// Torrents in list order, with their new URLs by tracker id.
+	order := []int{}
+	urls := map[int]map[int]string{}
+	for _, item := range state.List.Items {
+		change := item.(TrackerChange)
+		if _, ok := urls[change.TorrentId]; !ok {
+			order = append(order, change.TorrentId)
+			urls[change.TorrentId] = map[int]string{}
+		}
+		urls[change.TorrentId][change.TrackerId] = change.New
+	}
+
+	go func() {
+		defer cancel()
+
+		failed := 0
+		for _, id := range order {
+			if ctx.Err() != nil {
+				return
+			}
+
+			err := window.client.ReplaceTrackersContext(ctx, id, urls[id])
+			if err != nil {
+				failed += 1
+			}
+			window.setResult(id, err)
+			window.manager.Draw <- true
+		}
+
+		state.Applying, state.Applied = false, true
+		state.Error = &Message{ fmt.Sprintf("Updated %d of %d torrents, %d failed", len(order) - failed, len(order), failed) }
+		window.manager.Draw <- true
+	}()
+}
+
+func (window *TrackerChangesWindow) setResult(torrentId int, err error) {
+	items := window.state.List.Items
+	for index, item := range items {
+		change := item.(TrackerChange)
+		if change.TorrentId == torrentId {
+			change.Status, change.Error = CHANGE_OK, err
+			if err != nil {
+				change.Status = CHANGE_FAILED
+			}
+			items[index] = change
+		}
+	}
+}
+
+func (window *TrackerChangesWindow) Draw() {
+	drawTrackerChanges(window.window, window.state)
+}
+
+func (window *TrackerChangesWindow) Resize() {
+	window.window.SetWidth(window.window.Parent().Width())
+	window.window.SetHeight(window.window.Parent().Height())
+}
+
+func NewTrackerChangesWindow(
+	client *transmission.Client,
+	changes []TrackerChange,
+	obfuscated bool,
+	parent tui.Drawable,
+	manager *WindowManager,
+) *TrackerChangesWindow {
+	rows, cols := parent.MaxYX()
+
+	window := parent.Sub(0, 0, rows, cols)
+
+	formatter := func(
+		change interface{},
+		width int,
+		printer func(int, string),
+	) {
+		formatTrackerChange(change, width, obfuscated, printer)
+	}
+
+	items := make([]list.Identifiable, len(changes))
+	for index, change := range changes {
+		items[index] = change
+	}
+
+	state := &TrackerChangesState{
+		List: list.List{
+			window,
+			formatter,
+			TRACKER_CHANGES_HEADER_HEIGHT+2,
+			DETAILS_FOOTER_HEIGHT,
+			0,
+			0,
+			false,
+			0,
+			[]int{},
+			0,
+			items,
+		},
+		Obfuscated: obfuscated}
+
+	return &TrackerChangesWindow{
+		client,
+		window,
+		manager,
+		state}
+}
+
+/* Drawing */
+
+func formatTrackerChange(
+	item interface{},
+	width int,
+	obfuscated bool,
+	printer func(int, string),
+) {
+	change := item.(TrackerChange)
+
+	name, from, to := change.TorrentName, change.Old, change.New
+	if obfuscated {
+		name, from, to = utils.Obfuscate(name), utils.Obfuscate(from), utils.Obfuscate(to)
+	}
+
+	// Format: Status - Torrent - Old URL -> New URL
+	nameLength := utils.MaxInt(10, width / 4)
+	prefix := fmt.Sprintf("%-7s %s ", change.Status, padRunes(cropRunes(name, nameLength), nameLength))
+
+	urls := cropRunes(fmt.Sprintf("%s -> %s", from, to), utils.MaxInt(0, width - len([]rune(prefix))))
+	printer(0, padRunes(prefix + urls, width))
+}
+
+// Pads the text with spaces up to the length in runes.
+func padRunes(text string, length int) string {
+	return text + strings.Repeat(" ", utils.MaxInt(0, length - len([]rune(text))))
+}
+
+func drawTrackerChanges(window tui.Drawable, state *TrackerChangesState) {
+	window.Erase()
+	_, col := window.MaxYX()
+
+	// Summary.
+	torrents := map[int]bool{}
+	for _, item := range state.List.Items {
+		torrents[item.(TrackerChange).TorrentId] = true
+	}
+
+	tui.WithAttribute(tui.ATTR_BOLD, func() {
+		window.MovePrint(0, 0, "Replace tracker URLs")
+	})
+
+	status := "Preview, nothing is changed yet"
+	switch {
+	case state.Applying:
+		status = "Applying..."
+	case state.Applied:
+		status = "Done"
+	}
+	window.MovePrint(1, 0, fmt.Sprintf(
+		"%d URL(s) in %d torrent(s) | %s",
+		len(state.List.Items),
+		len(torrents),
+		status))
+
+	// Controls reminder.
+	window.MovePrint(2, 0, TRACKER_CHANGES_CONTROLS_TEXT)
+
+	// Separator.
+	window.HLine(3, 0, col)
+
+	// Legend.
+	nameLength := utils.MaxInt(10, col / 4)
+	window.MovePrintf(
+		TRACKER_CHANGES_HEADER_HEIGHT, 0,
+		"%-7s %s %s",
+		"Status", padRunes("Torrent", nameLength), "Announce URL")
+	window.HLine(TRACKER_CHANGES_HEADER_HEIGHT + 1, 0, col)
+
+	// Draw List.
+	state.List.Draw()
+
+	// Failure of the change under cursor, then the summary, then the
+	// change itself in full.
+	var message error = state.Error
+	if cursor := state.List.Cursor; cursor >= 0 && cursor < len(state.List.Items) {
+		change := state.List.Items[cursor].(TrackerChange)
+		switch {
+		case change.Error != nil:
+			message = change.Error
+		case state.Error == nil:
+			from, to := change.Old, change.New
+			if state.Obfuscated {
+				from, to = utils.Obfuscate(from), utils.Obfuscate(to)
+			}
+			message = &Message{ fmt.Sprintf("%s -> %s", from, to) }
+		}
+	}
+	drawError(window, message)
+}
+
+func showTrackerChangesCheatsheet(parent tui.Drawable, manager *WindowManager) {
+	items := []HelpItem{
+		HelpItem{ "RETURN", "Apply all changes" },
+		HelpItem{ "qh←ESC", "Go back, stop applying" },
+		HelpItem{ "jk↑↓", "Move cursor up and down" },
+	}
+
+	cheatsheet := NewCheatsheet(parent, items, manager)
+	manager.AddWindow(cheatsheet)
+}
@@ -0,0 +1,207 @@
+package windows
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+	"transform"
+	"transmission"
+	"tui"
+	"utils"
+)
+
+/* Drawing */
+
+func formatTracker(
+	item interface{},
+	width int,
+	obfuscated bool,
+	printer func(int, string),
+) {
+	tracker := item.(transmission.TrackerStat)
+	now := time.Now().Unix()
+
+	// Format: Tier - Seeds - Leech - Last announce - Next - URL
+	var last string
+	switch {
+	case !tracker.HasAnnounced:
+		last = "Never"
+	case tracker.LastAnnounceTimedOut:
+		last = "Timed out"
+	case tracker.LastAnnounceSucceeded:
+		last = fmt.Sprintf("OK, %s ago", formatTime(int32(now - tracker.LastAnnounceTime), false))
+	default:
+		last = "Failed"
+	}
+
+	next := "-"
+	switch {
+	case tracker.AnnounceState == transmission.TR_TRACKER_ACTIVE:
+		next = "Now"
+	case tracker.NextAnnounceTime > now:
+		next = formatTime(int32(tracker.NextAnnounceTime - now), false)
+	}
+
+	announce := tracker.Announce
+	if obfuscated {
+		announce = utils.Obfuscate(announce)
+	}
+
+	prefix := fmt.Sprintf(
+		"%4d %6s %6s %-18s %-8s ",
+		tracker.Tier + 1,
+		formatCount(tracker.SeederCount),
+		formatCount(tracker.LeecherCount),
+		last,
+		next,
+	)
+
+	urlLength := utils.MaxInt(0, width - len(prefix))
+	croppedAnnounce := cropRunes(announce, urlLength)
+	spacesLength := urlLength - len([]rune(croppedAnnounce))
+	printer(0, prefix + croppedAnnounce + strings.Repeat(" ", spacesLength))
+}
+
+// Scrape counts are -1 until the tracker answers.
+func formatCount(count int) string {
+	if count < 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%d", count)
+}
+
+func drawTrackersLegend(window tui.Drawable, row int, col int) {
+	// Legend: Tier - Seeds - Leech - Last announce - Next - URL
+	legendFormat := "%4s %6s %6s %-18s %-8s %s"
+	window.MovePrintf(
+		row, 0,
+		legendFormat, "Tier", "Seeds", "Leech", "Last announce", "Next", "Announce URL",
+	)
+	window.HLine(row + 1, 0, col)
+}
+
+// Full announce and scrape results of the tracker, which don't fit the row.
+func trackerResults(tracker transmission.TrackerStat) string {
+	announce := "not yet"
+	if tracker.HasAnnounced {
+		announce = tracker.LastAnnounceResult
+	}
+
+	scrape := "not yet"
+	if tracker.HasScraped {
+		scrape = tracker.LastScrapeResult
+		if bool(tracker.LastScrapeTimedOut) {
+			scrape = "timed out"
+		}
+	}
+
+	return fmt.Sprintf("Announce: %s | Scrape: %s", announce, scrape)
+}
+
+/* Actions */
+
+func promptAddTrackers(window *TorrentDetailsWindow) {
+	state := window.state
+
+	TextPrompt(
+		window.window,
+		window.manager,
+		"Add trackers (comma-separated):",
+		"",
+		func(value string) {
+			urls := parseLabels(value)
+			if len(urls) == 0 {
+				return
+			}
+
+			go func() {
+				editTrackers(window.client, state, func(id int) error {
+					return window.client.AddTrackers(id, urls)
+				})
+				window.manager.Draw <- true
+			}()
+		})
+}
+
+func promptReplaceTracker(window *TorrentDetailsWindow) {
+	state := window.state
+	if state.Trackers.Cursor < 0 || len(state.Trackers.Items) == 0 {
+		return
+	}
+
+	tracker := state.Trackers.Items[state.Trackers.Cursor].(transmission.TrackerStat)
+	TextPrompt(
+		window.window,
+		window.manager,
+		"Announce URL:",
+		tracker.Announce,
+		func(value string) {
+			url := strings.TrimSpace(value)
+			if url == "" || url == tracker.Announce {
+				return
+			}
+
+			go func() {
+				editTrackers(window.client, state, func(id int) error {
+					return window.client.ReplaceTracker(id, tracker.TrackerId, url)
+				})
+				window.manager.Draw <- true
+			}()
+		})
+}
+
+func confirmRemoveTrackers(window *TorrentDetailsWindow) {
+	state := window.state
+	if len(state.Trackers.Items) == 0 {
+		return
+	}
+
+	trackers := transform.ToTrackerList(state.Trackers.GetSelection())
+	ids := make([]int, len(trackers))
+	for index, tracker := range trackers {
+		ids[index] = tracker.TrackerId
+	}
+
+	message := fmt.Sprintf("Remove %d trackers?", len(trackers))
+	if len(trackers) == 1 {
+		message = fmt.Sprintf("Remove %s?", trackers[0].Host)
+		if state.Obfuscated {
+			message = fmt.Sprintf("Remove %s?", utils.Obfuscate(trackers[0].Host))
+		}
+	}
+
+	ConfirmPrompt(
+		window.window,
+		window.manager,
+		message,
+		func() {
+			state.Trackers.ClearSelection()
+			go func() {
+				editTrackers(window.client, state, func(id int) error {
+					return window.client.RemoveTrackers(id, ids)
+				})
+				window.manager.Draw <- true
+			}()
+		})
+}
+
+/* Network */
+
+func editTrackers(
+	client *transmission.Client,
+	state *TorrentDetailsState,
+	edit func(id int) error,
+) {
+	if state.Torrent == nil {
+		return
+	}
+
+	id := state.Torrent.Id
+	e := edit(id)
+
+	state.Error = e
+	if e == nil {
+		getDetails(context.Background(), client, id, state)
+	}
+}
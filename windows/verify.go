@@ -0,0 +1,392 @@
+package windows
+
+import (
+	"context"
+	"fmt"
+	"metainfo"
+	"path/filepath"
+	"strings"
+	"suggestions"
+	"sync/atomic"
+	"time"
+	"tui"
+	"utils"
+	"verify"
+)
+
+const (
+	VERIFY_FOCUS_TORRENT int = 0
+	VERIFY_FOCUS_DATA = 1
+	VERIFY_FOCUS_CONFIRM = 2
+	VERIFY_FOCUS_CANCEL = 3
+	VERIFY_FOCUS_COUNT = 4
+)
+
+// Rows for files that didn't check out.
+const VERIFY_FILES_HEIGHT = 8
+
+type VerifyState struct {
+	TorrentField *InputField
+	DataField *InputField
+	Focus int
+
+	// Hashing progress, updated from hashing goroutines.
+	Verifying bool
+	Checked int64
+	Total int64
+	cancel context.CancelFunc
+
+	Report *verify.Report
+	Error error
+}
+
+/* Window */
+
+// Checks local data against a .torrent file, without the daemon.
+type VerifyWindow struct {
+	parent tui.Drawable
+	window tui.Drawable
+	manager *WindowManager
+	obfuscated bool
+	state *VerifyState
+}
+
+func (window *VerifyWindow) IsFullScreen() bool {
+	return false
+}
+
+func (window *VerifyWindow) SetActive(active bool) {
+	field := window.focusedField()
+	if field == nil {
+		tui.HideCursor()
+		return
+	}
+
+	if active {
+		field.IsActive = true
+		window.manager.AddInputReader(field)
+	} else {
+		window.manager.RemoveInputReader(field)
+		tui.HideCursor()
+	}
+}
+
+func (window *VerifyWindow) focusedField() *InputField {
+	switch window.state.Focus {
+	case VERIFY_FOCUS_TORRENT:
+		return window.state.TorrentField
+	case VERIFY_FOCUS_DATA:
+		return window.state.DataField
+	}
+	return nil
+}
+
+func (dialog *VerifyWindow) Draw() {
+	window, state := dialog.window, dialog.state
+
+	window.Box()
+
+	_, col := window.MaxYX()
+	startX, width := 2, col-4
+
+	// Header
+	window.MovePrint(1, startX, "Verify local data")
+	window.HLine(2, 1, col-2)
+
+	// Fields
+	window.MovePrint(3, startX, "Torrent file:")
+	state.TorrentField.Draw()
+	window.MovePrint(5, startX, "Data directory (empty for the torrent's directory):")
+	state.DataField.Draw()
+	window.HLine(7, 1, col-2)
+
+	// Results
+	dialog.drawStatus(8, startX, width)
+	if state.Report != nil && !state.Verifying {
+		dialog.drawFiles(9, startX, width)
+	}
+
+	// Buttons
+	buttonsRow := 9 + VERIFY_FILES_HEIGHT
+	window.HLine(buttonsRow, 1, col-2)
+
+	confirm, cancel := "Verify", "Cancel"
+	if state.Report != nil {
+		cancel = "Close"
+	}
+
+	buttonWidth := width / 2
+	drawFocusable(
+		window,
+		state.Focus == VERIFY_FOCUS_CONFIRM && !state.Verifying,
+		buttonsRow + 1, startX + (buttonWidth - len(confirm)) / 2,
+		confirm)
+	drawFocusable(
+		window,
+		state.Focus == VERIFY_FOCUS_CANCEL || state.Verifying,
+		buttonsRow + 1, startX + buttonWidth + (buttonWidth - len(cancel)) / 2,
+		cancel)
+
+	// Enable cursor on input fields.
+	field := dialog.focusedField()
+	if field != nil && !state.Verifying {
+		tui.ShowCursor()
+	} else {
+		tui.HideCursor()
+	}
+
+	window.Redraw()
+
+	if field != nil && !state.Verifying {
+		field.SetCursor(window)
+	}
+}
+
+func (dialog *VerifyWindow) drawStatus(y, x, width int) {
+	window, state := dialog.window, dialog.state
+
+	switch {
+	case state.Verifying:
+		checked, total := atomic.LoadInt64(&state.Checked), atomic.LoadInt64(&state.Total)
+
+		var progress float64
+		if total > 0 {
+			progress = float64(checked) / float64(total)
+		}
+
+		window.MovePrint(y, x, formatProgress(progress, width))
+	case state.Error != nil:
+		window.MovePrint(y, x, cropRunes(fmt.Sprintf("%s", state.Error), width))
+	case state.Report != nil:
+		report := state.Report
+		message := fmt.Sprintf("All %d pieces are good", report.PieceCount)
+		if !report.Complete {
+			message = fmt.Sprintf(
+				"%d of %d pieces are good: %d missing, %d short, %d corrupt",
+				report.GoodPieces, report.PieceCount,
+				len(report.Missing), len(report.Short), len(report.Corrupt))
+		}
+		window.MovePrint(y, x, cropRunes(message, width))
+	default:
+		window.WithAttribute(tui.ATTR_DIM, func() {
+			window.MovePrint(y, x, "Pieces are hashed locally, the daemon isn't involved")
+		})
+	}
+}
+
+// Files that didn't check out, as many as fit.
+func (dialog *VerifyWindow) drawFiles(y, x, width int) {
+	files := dialog.state.Report.BadFiles()
+
+	shown := len(files)
+	if shown > VERIFY_FILES_HEIGHT {
+		shown = VERIFY_FILES_HEIGHT - 1
+	}
+
+	for index, file := range files[:shown] {
+		path := file.Path
+		if dialog.obfuscated {
+			path = utils.Obfuscate(path)
+		}
+		dialog.window.MovePrint(y + index, x, cropRunes(fmt.Sprintf("%-10s %s", file.Status, path), width))
+	}
+
+	if shown < len(files) {
+		dialog.window.MovePrintf(y + shown, x, "... and %d more", len(files) - shown)
+	}
+}
+
+func (window *VerifyWindow) Resize() {
+	height, width, y, x := MeasureVerifyWindow(window.parent)
+	window.state.TorrentField.Length = width - 4
+	window.state.DataField.Length = width - 4
+	window.window.Move(y, x)
+	window.window.Resize(height, width)
+}
+
+func MeasureVerifyWindow(parent tui.Drawable) (int, int, int, int) {
+	rows, cols := parent.MaxYX()
+
+	height, width := 11 + VERIFY_FILES_HEIGHT, utils.MinInt(cols, utils.MaxInt(60, cols * 3 / 4))
+	y, x := (rows - height) / 2, (cols - width) / 2
+	return height, width, y, x
+}
+
+func (window *VerifyWindow) OnInput(key tui.Key) {
+	state := window.state
+
+	// Only cancelling is possible while hashing.
+	if state.Verifying {
+		if key.ControlCode == tui.ASC_ESC || key.ControlCode == tui.ASC_ENTER {
+			state.cancel()
+			window.manager.RemoveWindow(window)
+		}
+		return
+	}
+
+	if key.ControlCode != 0 {
+		switch key.ControlCode {
+		case tui.ASC_TAB:
+			window.UpdateFocus(nil, 1)
+		case tui.ASC_ESC:
+			window.manager.RemoveWindow(window)
+		case tui.ASC_ENTER:
+			if state.Focus == VERIFY_FOCUS_CANCEL {
+				window.manager.RemoveWindow(window)
+			} else {
+				window.verify()
+			}
+		}
+	} else if key.EscapeSeq != nil {
+		switch *key.EscapeSeq {
+		case tui.ESC_LEFT, tui.ESC_UP:
+			window.UpdateFocus(nil, -1)
+		case tui.ESC_RIGHT, tui.ESC_DOWN:
+			window.UpdateFocus(nil, 1)
+		}
+	}
+}
+
+// Starts hashing in the background. Progress is redrawn a few times per
+// second until it's done.
+func (window *VerifyWindow) verify() {
+	state := window.state
+	state.Report = nil
+
+	torrent := strings.TrimSpace(utils.ExpandHome(string(state.TorrentField.Value)))
+	if torrent == "" {
+		state.Error = &Message{ "Pick a .torrent file to check against" }
+		window.redraw()
+		return
+	}
+
+	info, err := metainfo.Load(torrent)
+	if err != nil {
+		state.Error = err
+		window.redraw()
+		return
+	}
+
+	directory := strings.TrimSpace(utils.ExpandHome(string(state.DataField.Value)))
+	if directory == "" {
+		directory = filepath.Dir(torrent)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	state.cancel = cancel
+	state.Verifying, state.Error = true, nil
+	atomic.StoreInt64(&state.Checked, 0)
+	atomic.StoreInt64(&state.Total, 0)
+
+	progress := func(checked int64, total int64) {
+		atomic.StoreInt64(&state.Checked, checked)
+		atomic.StoreInt64(&state.Total, total)
+	}
+
+	type result struct {
+		report *verify.Report
+		err error
+	}
+
+	done := make(chan result)
+	go func() {
+		report, err := verify.Verify(ctx, info, directory, 0, progress)
+		done <- result{ report, err }
+	}()
+
+	go func() {
+		ticker := time.NewTicker(200 * time.Millisecond)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				window.manager.Draw <- true
+			case result := <-done:
+				if ctx.Err() != nil {
+					// Cancelled, dialog is gone.
+					return
+				}
+
+				state.Report, state.Error = result.report, result.err
+				state.Verifying = false
+				cancel()
+				window.manager.Draw <- true
+				return
+			}
+		}
+	}()
+
+	window.redraw()
+}
+
+func (window *VerifyWindow) redraw() {
+	go func() {
+		window.manager.Draw <- true
+	}()
+}
+
+func (window *VerifyWindow) HandleInputFieldUpdate(field *InputField, result InputFieldResult) {
+	switch result {
+	case FOCUS_FORWARD:
+		window.UpdateFocus(field, 1)
+	case FOCUS_BACKWARD:
+		window.UpdateFocus(field, -1)
+	case UPDATE:
+		window.redraw()
+	}
+}
+
+func (window *VerifyWindow) UpdateFocus(source *InputField, direction int) {
+	if source != nil {
+		source.IsActive = false
+		window.manager.RemoveInputReader(source)
+	}
+
+	window.state.Focus = (window.state.Focus + direction + VERIFY_FOCUS_COUNT) % VERIFY_FOCUS_COUNT
+
+	if newInput := window.focusedField(); newInput != nil {
+		newInput.IsActive = true
+		window.manager.AddInputReader(newInput)
+	}
+
+	window.redraw()
+}
+
+func NewVerifyWindow(parent tui.Drawable, manager *WindowManager, obfuscated bool) *VerifyWindow {
+	height, width, y, x := MeasureVerifyWindow(parent)
+	window := parent.Sub(y, x, height, width)
+
+	field := func(y int, suggester Suggester) *InputField {
+		return &InputField{
+			X: 2, Y: y, Length: width - 4,
+			IsModal: false,
+			EnterToConfirm: false,
+			IsActive: false,
+			Value: []rune{},
+			Charset: "",
+			Suggester: suggester,
+			Suggestion: nil,
+			Manager: manager,
+			Parent: window,
+			OnResult: nil,
+		}
+	}
+
+	state := &VerifyState{
+		TorrentField: field(4, suggestions.GetSuggestedFiles),
+		DataField: field(6, suggestions.GetSuggestedDirs),
+	}
+
+	dialog := &VerifyWindow{
+		parent,
+		window,
+		manager,
+		obfuscated,
+		state}
+
+	state.TorrentField.OnResult = dialog.HandleInputFieldUpdate
+	state.DataField.OnResult = dialog.HandleInputFieldUpdate
+
+	return dialog
+}
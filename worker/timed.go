@@ -1,35 +1,45 @@
 package worker
 
 import (
+	"context"
 	"time"
 )
 
 type RepeatingWorker struct {
 	interval int
-	job func()
-	suspend chan bool
+	job func(context.Context)
+	cancel context.CancelFunc
 }
 
-func Repeating(interval int, job func()) Worker {
-	return &RepeatingWorker{ interval, job, make(chan bool) }
+// Runs the job immediately and then every 'interval' seconds until stopped.
+// Stopping the worker cancels the context passed to the job, so any
+// in-flight requests made with it are aborted too.
+func Repeating(interval int, job func(context.Context)) Worker {
+	return &RepeatingWorker{ interval, job, nil }
 }
 
 func (worker *RepeatingWorker) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	worker.cancel = cancel
+
 	go func() {
 		// Initial run.
-		worker.job()
+		worker.job(ctx)
 
 		for {
 			select {
-			case <-worker.suspend:
+			case <-ctx.Done():
 				return
 			case <-time.After(time.Duration(worker.interval) * time.Second):
-				worker.job()
+				worker.job(ctx)
 			}
 		}
 	}()
 }
 
 func (worker *RepeatingWorker) Stop() {
-	worker.suspend <- true
+	if worker.cancel != nil {
+		worker.cancel()
+		worker.cancel = nil
+	}
 }